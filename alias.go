@@ -0,0 +1,243 @@
+package autocfg
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/davidwalter0/go-cfg"
+	eflag "github.com/davidwalter0/go-flag"
+)
+
+const (
+	// AliasTag names the struct tag listing former names of a field,
+	// e.g. `json:"vault-address" alias:"vault-addr"`
+	AliasTag = "alias"
+	// DeprecatedTag names the struct tag with the migration message
+	// warned when a field is set by an alias, or by its own name when
+	// the field has no aliases
+	DeprecatedTag = "deprecated"
+)
+
+// aliases of a field from its alias tag
+func aliases(sf reflect.StructField) (names []string) {
+	for _, name := range strings.Split(sf.Tag.Get(AliasTag), ",") {
+		if name = strings.TrimSpace(name); len(name) > 0 {
+			names = append(names, name)
+		}
+	}
+	return
+}
+
+// deprecation message of a field, the deprecated tag or "use current"
+func deprecation(sf reflect.StructField, current string) string {
+	if text := sf.Tag.Get(DeprecatedTag); len(text) > 0 {
+		return text
+	}
+	return "use " + current
+}
+
+// findKey of a json object matching name like encoding/json, exactly
+// then case insensitively
+func findKey(object map[string]any, name string) (string, bool) {
+	if _, ok := object[name]; ok {
+		return name, true
+	}
+	for key := range object {
+		if strings.EqualFold(key, name) {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// migrate renames alias keys in the json text of the file at path to
+// the current field names, warning with the line of each deprecated
+// key. The text is returned unchanged when nothing is renamed.
+func migrate(text []byte, obj any, path string) []byte {
+	var tree = decodeTree(text)
+	if tree == nil {
+		return text
+	}
+	var lines = keyLines(text)
+	if !migrateTree(tree, reflect.TypeOf(obj), nil, lines, path) {
+		return text
+	}
+	if out, err := json.Marshal(tree); err == nil {
+		return out
+	}
+	return text
+}
+
+// migrateTree walks a decoded json value alongside the Go type it
+// decodes into, renaming alias keys in place
+func migrateTree(node any, t reflect.Type, at []string, lines map[string]int, path string) (changed bool) {
+	t = elemType(t)
+	if t == nil {
+		return
+	}
+	var key = func(k string) []string {
+		return append(append([]string{}, at...), k)
+	}
+	switch v := node.(type) {
+	case map[string]any:
+		if t.Kind() == reflect.Map {
+			for k, value := range v {
+				changed = migrateTree(value, t.Elem(), key(k), lines, path) || changed
+			}
+			return
+		}
+		if t.Kind() != reflect.Struct || isScalarStruct(t) {
+			return
+		}
+		for _, sf := range structFields(t) {
+			var name = jsonName(sf)
+			var names = aliases(sf)
+			if len(names) == 0 && len(sf.Tag.Get(DeprecatedTag)) > 0 {
+				if k, ok := findKey(v, name); ok {
+					warn("%s: key %s is deprecated, %s", location(path, lines, key(k)), k, sf.Tag.Get(DeprecatedTag))
+				}
+				continue
+			}
+			for _, alias := range names {
+				var k, ok = findKey(v, alias)
+				if !ok {
+					continue
+				}
+				if current, set := findKey(v, name); set {
+					warn("%s: key %s is deprecated and ignored, %s is set", location(path, lines, key(k)), k, current)
+				} else {
+					warn("%s: key %s is deprecated, %s", location(path, lines, key(k)), k, deprecation(sf, name))
+					v[name] = v[k]
+				}
+				delete(v, k)
+				changed = true
+			}
+		}
+		for k, value := range v {
+			if sf, ok := lookupField(t, k); ok {
+				changed = migrateTree(value, sf.Type, key(k), lines, path) || changed
+			}
+		}
+	case []any:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return
+		}
+		for i, value := range v {
+			changed = migrateTree(value, t.Elem(), key(strconv.Itoa(i)), lines, path) || changed
+		}
+	}
+	return
+}
+
+// aliasFlag is a deprecated flag name setting the flag Name
+type aliasFlag struct {
+	Name    string
+	Message string
+}
+
+// aliasFlags maps deprecated flag names to the current flag
+var aliasFlags = map[string]aliasFlag{}
+
+// renamed replaces the trailing field part of a go-cfg flag or env
+// name with alias, alias alone when name does not end with part
+func renamed(name, part, alias string) string {
+	if strings.HasSuffix(name, part) {
+		return strings.TrimSuffix(name, part) + alias
+	}
+	return alias
+}
+
+// defineAliases adds a hidden flag for each alias of the fields of
+// obj, sharing the value of the field's flag
+func defineAliases(obj any) {
+	var flags = flagsByAddr()
+	for _, l := range leaves(obj) {
+		var flag, ok = flags[l.Addr()]
+		if !ok || !allowed(l.Field, FlagLayer) {
+			continue
+		}
+		for _, alias := range aliases(l.Field) {
+			var name = renamed(flag.Name, cfg.ToLowerKebabCase(jsonName(l.Field)), alias)
+			if eflag.Lookup(name) != nil {
+				continue
+			}
+			var message = deprecation(l.Field, "--"+flag.Name)
+			eflag.CommandLine.Var(flag.Value, name, "deprecated, "+message, false, false)
+			aliasFlags[name] = aliasFlag{Name: flag.Name, Message: message}
+			hidden[name] = true
+		}
+	}
+	hideUsage()
+}
+
+// cfgEnvName is the go-cfg env var name of a json key path, without
+// a CFG_KEY_PREFIX or decoration
+func cfgEnvName(path []string) string {
+	return cfg.ToUpperSnakeCase(strings.ReplaceAll(strings.Join(path, "_"), "-", "_"))
+}
+
+// cfgFlagName is the go-cfg flag name of a json key path, without a
+// prefix or decoration
+func cfgFlagName(path []string) string {
+	return cfg.ToLowerKebabCase(strings.ReplaceAll(strings.Join(path, "-"), "_", "-"))
+}
+
+// envName of the env var setting the leaf, empty without one
+func envName(l leaf, flags map[uintptr]*eflag.Flag) string {
+	if envPolicy != nil {
+		return envPolicy.Name(l.Path)
+	}
+	if flag, ok := flags[l.Addr()]; ok {
+		return flagEnvName(flag)
+	}
+	return ""
+}
+
+// aliasEnv sets fields of obj from the env vars of their aliases when
+// the current env var is unset, warning for each one used
+func aliasEnv(obj any) (err error) {
+	var flags = flagsByAddr()
+	for _, l := range leaves(obj) {
+		var names = aliases(l.Field)
+		var current = envName(l, flags)
+		if len(names) == 0 || len(current) == 0 || !allowed(l.Field, EnvLayer) {
+			continue
+		}
+		if value, set := LookupEnv(current); set && len(value) > 0 {
+			continue
+		}
+		for _, alias := range names {
+			var name = renamed(current, cfgEnvName([]string{jsonName(l.Field)}), cfgEnvName([]string{alias}))
+			if envPolicy != nil {
+				name = envPolicy.Name(append(append([]string{}, l.Path[:len(l.Path)-1]...), alias))
+			}
+			var text, set = LookupEnv(name)
+			if !set {
+				continue
+			}
+			if err = setValue(l.Value, text); err != nil {
+				return
+			}
+			setSource(l.Key(), envSource(name))
+			warn("env %s is deprecated, %s", name, deprecation(l.Field, current))
+			break
+		}
+	}
+	return
+}
+
+// warnDeprecated warns for fields with a deprecated tag and no alias
+// set by env or flag, files warn with the line when loaded
+func warnDeprecated(obj any) {
+	for _, l := range leaves(obj) {
+		var text = l.Field.Tag.Get(DeprecatedTag)
+		if len(text) == 0 || len(aliases(l.Field)) > 0 {
+			continue
+		}
+		if source, ok := provenance[l.Key()]; ok && (source.Layer == EnvLayer || source.Layer == DotenvLayer || source.Layer == FlagLayer) {
+			warn("%s %s is deprecated, %s", source.Layer, source.Name, text)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package autocfg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type aliasConf struct {
+	VaultAddress string `json:"vault-address" alias:"vault-addr"`
+	VaultToken   string `json:"vault-token" deprecated:"use a token file"`
+}
+
+func TestAliasFile(t *testing.T) {
+	defer Reset()
+	Reset()
+	var file = filepath.Join(t.TempDir(), "config.json")
+	var text = "{\n  \"vault-addr\": \"https://vault:8200\",\n  \"vault-token\": \"s.token\"\n}\n"
+	if err := os.WriteFile(file, []byte(text), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var o = &aliasConf{}
+	if err := LoadDirect(file, o); err != nil {
+		t.Fatal(err)
+	}
+	if o.VaultAddress != "https://vault:8200" || o.VaultToken != "s.token" {
+		t.Errorf("alias did not set vault-address %+v", o)
+	}
+	var warnings = Warnings()
+	if len(warnings) != 2 || !strings.Contains(warnings[0], file+":2") || !strings.Contains(warnings[1], file+":3") {
+		t.Errorf("expected warnings naming file and line got %q", warnings)
+	}
+}
+
+func TestAliasFlagEnv(t *testing.T) {
+	var args = os.Args
+	defer func() {
+		os.Args = args
+		Reset()
+	}()
+	for _, c := range []struct {
+		args []string
+		env  string
+	}{
+		{args: []string{"--vault-addr=https://flag:8200"}},
+		{env: "https://env:8200"},
+	} {
+		Reset()
+		os.Args = append([]string{"autocfg.test"}, c.args...)
+		if len(c.env) > 0 {
+			Setenv("VAULT_ADDR", c.env, false)
+		}
+		var o = &aliasConf{}
+		if err := Configure(o); err != nil {
+			t.Fatal(err)
+		}
+		if want := strings.TrimPrefix(strings.Join(c.args, ""), "--vault-addr=") + c.env; o.VaultAddress != want {
+			t.Errorf("expected %s got %+v", want, o)
+		}
+		if len(Warnings()) != 1 || !strings.Contains(Warnings()[0], "deprecated") {
+			t.Errorf("expected a deprecation warning got %q", Warnings())
+		}
+	}
+}
+
+type aliasRenamedConf struct {
+	VaultAddr string `json:"vault-address" alias:"vault-addr"`
+	Mount     string `json:"auth-mount" alias:"mount"`
+}
+
+// the go-cfg names follow the json names, not the field names
+func TestAliasJSONName(t *testing.T) {
+	var args = os.Args
+	defer func() {
+		os.Args = args
+		Reset()
+	}()
+	Reset()
+	os.Args = []string{"autocfg.test", "--vault-addr", "https://flag:8200"}
+	Setenv("MOUNT", "approle", false)
+	var o = &aliasRenamedConf{}
+	if err := Configure(o); err != nil {
+		t.Fatal(err)
+	}
+	if o.VaultAddr != "https://flag:8200" || o.Mount != "approle" {
+		t.Errorf("expected the flag and env aliases of renamed fields got %+v", o)
+	}
+}
@@ -49,7 +49,8 @@ func SetSimpleMode(filename string) (err error) {
 	return
 }
 
-// Strict forces finding a configuration file
+// Strict forces finding a configuration file and turns unknown keys
+// in configuration files into ErrUnknownKey errors
 var Strict bool
 
 /*
@@ -139,6 +140,29 @@ func SearchModeName(mode SearchMode) (name string) {
 	return
 }
 
+// ParseSearchMode parses a SearchModeName, parts separated by "-",
+// "," or "|" in any case, e.g. "union-direct-indirect" or "First,Direct"
+func ParseSearchMode(text string) (m SearchMode, err error) {
+	defer Trace.ScopedTrace()()
+	var parts = strings.FieldsFunc(text, func(r rune) bool {
+		return r == '-' || r == ',' || r == '|' || r == ' '
+	})
+	if len(parts) == 0 {
+		return 0, fmt.Errorf("empty search mode")
+	}
+next:
+	for _, part := range parts {
+		for bit, name := range SearchModeMap {
+			if strings.EqualFold(part, name) {
+				m |= bit
+				continue next
+			}
+		}
+		return 0, fmt.Errorf("unknown search mode %q", part)
+	}
+	return
+}
+
 // mode The mode selected
 var mode SearchMode = Simple //  | Indirect | Direct // Union | Direct
 
@@ -172,77 +196,64 @@ func Mode() string {
 }
 
 // AutoCfg auto config format specifies the path of a configuration
-// file to load and an environment variable map
+// file to load and an environment variable map. Env entries are
+// visible to ${VAR} expansion of the configuration file and to the
+// env layer, but only reach the process environment when Export or
+// ExportEnv is set.
 type AutoCfg struct {
-	Path string            `json:"path"   doc:"where to find the config spec file path"`
-	Env  map[string]string `json:"env"    doc:"env var setup map[name]value"`
+	Path   string            `json:"path"   doc:"where to find the config spec file path"`
+	Env    map[string]string `json:"env"    doc:"env var setup map[name]value"`
+	Export bool              `json:"export,omitempty" doc:"export env to the process environment"`
+	// Interpolate when false loads the configuration file verbatim
+	Interpolate *bool `json:"interpolate,omitempty" doc:"expand ${VAR} in configuration string values"`
 }
 
 var pgm = strings.TrimSuffix(path.Base(os.Args[0]), path.Ext(os.Args[0]))
 
-func generator(path string, obj any) (err error) {
+// SetProgram replaces the program name derived from os.Args[0] in
+// search paths, to find the configuration of another application
+func SetProgram(name string) {
 	defer Trace.ScopedTrace()()
-	var file *os.File
-	file, err = os.Create(path)
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer file.Close()
-	var text []byte
-	text, err = json.MarshalIndent(obj, "", "  ")
-	file.Write(text)
-	if err != nil {
-		log.Fatal(err)
-	}
-	return
+	pgm = name
 }
 
-// Generator empty sample configuration files using the default
+// Program name used in search paths
+func Program() string {
+	defer Trace.ScopedTrace()()
+	return pgm
+}
+
+// Generator writes sample configuration files using the default
 // autocfg type and an example object and place them in
 // /tmp/dot.autocfg.json pointing it's path to /tmp/dot.config.json
 // These can use used to confirm format and values of the arguments.
-// Notice that if omitempty or similar json parameters are present in
-// the tags the json Marshaling of the object are not included in the
-// example configuration. Replace prior definition when overwrite is
-// true
-func Generator(obj any, overwrite bool) {
+// Every field is written, omitempty fields included, see
+// GenerateTemplate. Replace prior definition when overwrite is true
+func Generator(obj any, overwrite bool) (err error) {
 	defer Trace.ScopedTrace()()
-	var err error
-	var path = "/tmp/dot.autocfg.json"
-	var config = "/tmp/dot.config.json"
-
-	// only
-
-	if _, err = os.Stat(path); overwrite || errors.Is(err, fs.ErrNotExist) {
-		generator(path, &AutoCfg{Path: config})
-	}
-	if _, err = os.Stat(config); overwrite || errors.Is(err, fs.ErrNotExist) {
-		generator(config, obj)
-	}
+	return generate("/tmp/dot.autocfg.json", "/tmp/dot.config.json", obj, overwrite)
 }
 
-// LocalGenerator empty sample configuration files using the default
+// LocalGenerator writes sample configuration files using the default
 // autocfg type and an example object and place them in
 // dot.autocfg.json pointing it's path to dot.config.json
 // These can use used to confirm format and values of the arguments.
-// Notice that if omitempty or similar json parameters are present in
-// the tags the json Marshaling of the object are not included in the
-// example configuration. Replace prior definition when overwrite is
-// true
-func LocalGenerator(obj any, overwrite bool) {
+// Every field is written, omitempty fields included, see
+// GenerateTemplate. Replace prior definition when overwrite is true
+func LocalGenerator(obj any, overwrite bool) (err error) {
 	defer Trace.ScopedTrace()()
-	var err error
-	var path = "dot.autocfg.json"
-	var config = "dot.config.json"
-
-	// only
+	return generate("dot.autocfg.json", "dot.config.json", obj, overwrite)
+}
 
-	if _, err = os.Stat(path); overwrite || errors.Is(err, fs.ErrNotExist) {
-		generator(path, &AutoCfg{Path: config})
+// generate the autocfg file at path pointing to the config template
+func generate(path, config string, obj any, overwrite bool) (err error) {
+	if err = GenerateTemplate(path, &AutoCfg{Path: config}, overwrite); err != nil && !errors.Is(err, fs.ErrExist) {
+		return
 	}
-	if _, err = os.Stat(config); overwrite || errors.Is(err, fs.ErrNotExist) {
-		generator(config, obj)
+	if err = GenerateTemplate(config, obj, overwrite); errors.Is(err, fs.ErrExist) {
+		err = nil
 	}
+	return
 }
 
 func isPtr(obj any) (rc bool) {
@@ -298,6 +309,9 @@ func configure(obj any) (err error) {
 	if !isPtr(obj) {
 		return fmt.Errorf("arg obj any [%T]: object is not a pointer to struct", obj)
 	}
+	if err = scanStandardFlags(obj); err != nil {
+		return
+	}
 	var found bool
 	defer func() {
 		defer Trace.ScopedTrace("!Strict")()
@@ -309,6 +323,11 @@ func configure(obj any) (err error) {
 			}
 		}
 	}()
+	if Dotenv {
+		if err = LoadDotenvFiles(); err != nil {
+			return
+		}
+	}
 	var direct, indirect []string
 	if Direct&mode == Direct {
 		direct = DirectFiles()
@@ -331,7 +350,9 @@ func configure(obj any) (err error) {
 // configuration obeying rule of 'union dominance' replacing any
 // attribute(s) set by the next unmarshaled configuration. The last
 // attribute(s) unmarshaled dominate - replace prior unmarshaling
-// calls.
+// calls. A file with errors is reported as a warning and the others
+// still load, the errors are returned when Strict is set or for
+// sources and policy violations.
 //
 // The application name is evaluated from the binary name AKA
 // filepath.Base(os.Args[0])
@@ -369,43 +390,59 @@ func Load(obj any, direct, indirect []string) (err error) {
 	var shortCircuit = mode&First == First
 	if shortCircuit {
 		for _, path := range direct {
-			if err = LoadDirect(path, obj); err == nil {
-				fmt.Fprintf(os.Stderr, "LoadDirect %v\n", err)
-				if shortCircuit {
-					return
-				}
+			if path = ExpandEnvEvalTilde(path); exists(path) {
+				err = LoadDirect(path, obj)
+				fmt.Fprintf(os.Stderr, "LoadDirect %s %v\n", path, err)
+				return
 			}
 		}
 		for _, path := range indirect {
-			if err = LoadIndirect(path, obj); err == nil {
-				fmt.Fprintf(os.Stderr, "LoadIndirect %v\n", err)
-				if shortCircuit {
-					return
-				}
+			if path = ExpandEnvEvalTilde(path); exists(path) {
+				err = LoadIndirect(path, obj)
+				fmt.Fprintf(os.Stderr, "LoadIndirect %s %v\n", path, err)
+				return
 			}
 		}
 	} else {
+		// every file found loads, errors are reported together
+		var errs []error
+		var report = func(path string, err error) {
+			if err == nil {
+				return
+			}
+			var pe *ParseError
+			if !errors.As(err, &pe) || pe.Path != path {
+				err = fmt.Errorf("%s: %w", path, err)
+			}
+			if !Strict && !errors.Is(err, ErrSourceNotAllowed) && !errors.Is(err, ErrPolicyLocked) {
+				warn("%v", err)
+				return
+			}
+			errs = append(errs, err)
+		}
+		// AUTOCFG_FILENAME is in both lists, a plain config there
+		// loads as a direct file only
 		for _, path := range indirect {
-			_ = LoadIndirect(path, obj)
+			if path = ExpandEnvEvalTilde(path); exists(path) && isAutoCfg(path) {
+				report(path, LoadIndirect(path, obj))
+			}
 		}
 		for _, path := range direct {
-			_ = LoadDirect(path, obj)
+			if path = ExpandEnvEvalTilde(path); exists(path) {
+				report(path, LoadDirect(path, obj))
+			}
 		}
-		// for _, path := range indirect {
-		// 	if err = LoadIndirect(path, obj); err == nil {
-		// 		fmt.Fprintf(os.Stderr, "LoadIndirect %v\n", err)
-		// 	}
-		// }
-		// for _, path := range direct {
-		// 	if err = LoadDirect(path, obj); err == nil {
-
-		// 	}
-		// }
-
+		err = errors.Join(errs...)
 	}
 	return
 }
 
+// exists reports whether a file is present at path
+func exists(path string) bool {
+	var _, err = os.Stat(path)
+	return err == nil
+}
+
 // IndirectLoad searches 3 paths for an indirect autocfg config
 // file. Found files are unmarshaled to an autocfg object argument.
 // The file is then parsed for it's path argument pointing to a
@@ -461,7 +498,7 @@ func IndirectLoad(obj any) (err error) {
 		}
 		return fmt.Sprintf(".%s.json", pgm)
 	}()
-	var ePath = os.Getenv("AUTOCFG_FILENAME")
+	var ePath = Getenv("AUTOCFG_FILENAME")
 	if Debug() {
 		fmt.Println(etc)
 		fmt.Println(config)
@@ -514,6 +551,10 @@ func Dump(obj any) {
 // Configure an object automagically
 func Configure(obj any) (err error) {
 	defer Trace.ScopedTrace()()
+	var define = func(ptr any) error { return cfg.Add(ptr) }
+	if precedence != nil {
+		return layered(obj, define)
+	}
 	if err = configure(obj); err != nil {
 		return
 	}
@@ -521,14 +562,18 @@ func Configure(obj any) (err error) {
 		fmt.Fprintf(os.Stderr, "\nafter configure\n")
 		Dump(obj)
 	}
-	if err = cfg.Flags(obj); err != nil {
+	if err = defineFlags(obj, define); err != nil {
 		log.Print(err)
 		//    log.Fatal(err)
-		if !Strict {
+		if !Strict && !errors.Is(err, ErrSourceNotAllowed) && !errors.Is(err, ErrPolicyLocked) {
 			err = nil
 		}
 		return
 	}
+	if err = ResolveReferences(obj); err != nil {
+		return
+	}
+	runStandardFlags(obj)
 	if Debug() {
 		fmt.Fprintf(os.Stderr, "\nafter cfg.Flags\n")
 		Dump(obj)
@@ -539,17 +584,23 @@ func Configure(obj any) (err error) {
 // MultiCallConfigure an object automagically
 func MultiCallConfigure(obj any) (err error) {
 	defer Trace.ScopedTrace()()
+	var define = func(ptr any) error {
+		cfg.Decorate()
+		return cfg.Nest(ptr)
+	}
+	if precedence != nil {
+		return layered(obj, define)
+	}
 	if err = configure(obj); err != nil {
-		panic(err)
 		return
 	}
 	if Debug() {
 		fmt.Fprintf(os.Stderr, "\nafter configure\n")
 		Dump(obj)
 	}
-	cfg.Decorate()
-	err = cfg.Nest(obj)
-	cfg.Freeze()
+	if err = defineFlags(obj, define); err != nil {
+		return
+	}
 	// if err = cfg.Nest(obj); err != nil {
 	//  log.Print(err)
 	//  //    log.Fatal(err)
@@ -558,6 +609,10 @@ func MultiCallConfigure(obj any) (err error) {
 	//    err = nil
 	//  }
 	// }
+	if err = ResolveReferences(obj); err != nil {
+		return
+	}
+	runStandardFlags(obj)
 	if Debug() {
 		fmt.Fprintf(os.Stderr, "\nafter cfg.Flags\n")
 		Dump(obj)
@@ -568,8 +623,16 @@ func MultiCallConfigure(obj any) (err error) {
 // UnprefixedMultiCallConfigure an object automagically
 func UnprefixedMultiCallConfigure(obj any) (err error) {
 	defer Trace.ScopedTrace()()
+	var define = func(ptr any) error {
+		if err := cfg.Unprefixed(ptr); err != nil {
+			log.Print(err)
+		}
+		return nil
+	}
+	if precedence != nil {
+		return layered(obj, define)
+	}
 	if err = configure(obj); err != nil {
-		panic(err)
 		return
 	}
 	if Debug() {
@@ -579,13 +642,10 @@ func UnprefixedMultiCallConfigure(obj any) (err error) {
 	//  cfg.Decorate()
 	// var args = cfg.NewArg("")
 	// args.Prefixed = true
-	err = cfg.Unprefixed(obj)
-	if err != nil {
-		log.Print(err)
-		//    log.Fatal(err)
-	}
 	//  err = cfg.Nest(obj)
-	cfg.Freeze()
+	if err = defineFlags(obj, define); err != nil {
+		return
+	}
 	// if err = cfg.Nest(obj); err != nil {
 	//  log.Print(err)
 	//  //    log.Fatal(err)
@@ -594,6 +654,10 @@ func UnprefixedMultiCallConfigure(obj any) (err error) {
 	//    err = nil
 	//  }
 	// }
+	if err = ResolveReferences(obj); err != nil {
+		return
+	}
+	runStandardFlags(obj)
 	if Debug() {
 		fmt.Fprintf(os.Stderr, "\nafter cfg.Flags\n")
 		Dump(obj)
@@ -604,23 +668,29 @@ func UnprefixedMultiCallConfigure(obj any) (err error) {
 // PrefixMultiCallConfigure an object automagically with prefix to flag args
 func PrefixMultiCallConfigure(prefix string, obj any) (err error) {
 	defer Trace.ScopedTrace()()
+	var define = func(ptr any) error { return cfg.Reprefix(prefix, ptr) }
+	if precedence != nil {
+		return layered(obj, define)
+	}
 	if err = configure(obj); err != nil {
-		panic(err)
 		return
 	}
 	if Debug() {
 		fmt.Fprintf(os.Stderr, "\nafter configure\n")
 		Dump(obj)
 	}
-	if err = cfg.Reprefix(prefix, obj); err != nil {
-		log.Fatal(err)
+	if err = defineFlags(obj, define); err != nil {
+		return
 	}
-	cfg.Freeze()
 	//  panic(err)
 	//  if !Strict {
 	//    err = nil
 	//  }
 	// }
+	if err = ResolveReferences(obj); err != nil {
+		return
+	}
+	runStandardFlags(obj)
 	if Debug() {
 		fmt.Fprintf(os.Stderr, "\nafter cfg.Flags\n")
 		Dump(obj)
@@ -632,7 +702,10 @@ func PrefixMultiCallConfigure(prefix string, obj any) (err error) {
 // argument
 func Usage(addText string) {
 	defer Trace.ScopedTrace()()
-	cfg.HelpText(addText)
+	if len(envHelpText) > 0 {
+		addText = strings.TrimRight(addText, "\n") + "\n\n" + envHelpText
+	}
+	setHelpText(addText)
 	cfg.Usage()
 }
 
@@ -693,29 +766,41 @@ func LoadIndirect(path string, obj any) (err error) {
 	if text, err = os.ReadFile(path); err != nil {
 		return
 	}
-	text = []byte(os.ExpandEnv(string(text)))
 	var autoCfg = &AutoCfg{}
-
-	if err = json.Unmarshal(text, autoCfg); err != nil {
+	var source = text
+	var standard []byte
+	var origin func(int64) int64
+	if standard, origin, err = standardJSON(path, text); err != nil {
 		return
 	}
+	text = standard
+	if interpolates(path) {
+		if text, err = interpolateJSON(text, autoCfg); err != nil {
+			return
+		}
+	}
+	if err = json.Unmarshal(text, autoCfg); err != nil {
+		return parseError(path, source, standard, origin, err)
+	}
 	if len(autoCfg.Path) == 0 {
 		err = fmt.Errorf("%w empty config path", fs.ErrInvalid)
+		return
 	}
-	if autoCfg.Path, err = homedir.Expand(os.ExpandEnv(autoCfg.Path)); err != nil {
+	// env applies before the config is read so it may use the vars
+	if err = applyEnv(autoCfg.Env, autoCfg.Export); err != nil {
 		return
 	}
-	if _, err = os.Stat(autoCfg.Path); err != nil {
+	if autoCfg.Path, err = homedir.Expand(autoCfg.Path); err != nil {
 		return
 	}
-	if text, err = os.ReadFile(autoCfg.Path); err == nil {
-		text = []byte(os.ExpandEnv(string(text)))
-		err = json.Unmarshal(text, obj)
+	if _, err = os.Stat(autoCfg.Path); err != nil {
+		return
 	}
-	for k, v := range autoCfg.Env {
-		os.Setenv(k, v)
+	if text, err = os.ReadFile(autoCfg.Path); err != nil {
+		return
 	}
-	return
+	var expand = interpolates(autoCfg.Path) && (autoCfg.Interpolate == nil || *autoCfg.Interpolate)
+	return loadFile(text, obj, autoCfg.Path, expand)
 }
 
 // LoadDirect read an application config file
@@ -734,8 +819,11 @@ func LoadDirect(path string, obj any) (err error) {
 		return
 	}
 	if text, err = os.ReadFile(path); err == nil {
-		text = []byte(os.ExpandEnv(string(text)))
-		err = json.Unmarshal(text, obj)
+		if isCallFile(path) && reflect.TypeOf(obj) == reflect.TypeOf(view.obj) {
+			err = view.loadCallFile(path, text, obj)
+		} else {
+			err = loadFile(text, obj, path, interpolates(path))
+		}
 		if Debug() {
 			fmt.Printf("> LoadDirect %s %v\n", path, err)
 		}
@@ -755,8 +843,15 @@ func reverse[S ~[]E, E any](s S) {
 // configuration
 func DirectFiles() (paths []string) {
 	defer Trace.ScopedTrace()()
+	if precedence != nil {
+		paths, _ = precedenceFiles()
+		if mode&First == First {
+			reverse(paths)
+		}
+		return
+	}
 	paths = []string{".config.json"}
-	var ePath = os.Getenv("AUTOCFG_FILENAME")
+	var ePath = Getenv("AUTOCFG_FILENAME")
 	var etc = fmt.Sprintf("/etc/%s/config.json", pgm)
 	var config = fmt.Sprintf("${HOME}/.config/%s/config.json", pgm)
 	var local = func() string {
@@ -766,10 +861,14 @@ func DirectFiles() (paths []string) {
 		return fmt.Sprintf(".%s.json", pgm)
 	}()
 	if len(ePath) > 0 {
-		paths = append(paths, []string{ePath, local, config, etc}...)
-	} else {
-		paths = append(paths, []string{local, config, etc}...)
+		paths = append(paths, ePath)
 	}
+	paths = append(paths, local)
+	// a multicall call file ranks ahead of the program config file
+	if view != nil && len(view.file) > 0 {
+		paths = append(paths, view.file)
+	}
+	paths = append(paths, []string{config, etc}...)
 	if mode&Union == Union {
 		reverse(paths)
 	}
@@ -779,8 +878,15 @@ func DirectFiles() (paths []string) {
 // IndirectFiles returns the list of auto config search paths
 func IndirectFiles() (paths []string) {
 	defer Trace.ScopedTrace()()
+	if precedence != nil {
+		_, paths = precedenceFiles()
+		if mode&First == First {
+			reverse(paths)
+		}
+		return
+	}
 	paths = []string{}
-	var ePath = os.Getenv("AUTOCFG_FILENAME")
+	var ePath = Getenv("AUTOCFG_FILENAME")
 	if len(ePath) > 0 {
 		paths = []string{ePath, AutoConfigPath(), LocalConfigPath()}
 	} else {
@@ -810,6 +916,9 @@ func SearchPaths() (list []string) {
 func String() (text string) {
 	defer Trace.ScopedTrace()()
 	text = fmt.Sprintf("Search mode = %s\n", SearchModeName(mode))
+	if precedence != nil {
+		text += fmt.Sprintf("Precedence = %s\n", strings.Join(precedence, ", "))
+	}
 
 	text += fmt.Sprintf(`Direct load paths -- direct load paths are
 configuration file names to attempt to load
@@ -828,31 +937,53 @@ configuration file names to attempt to load
 				ExpandEnvEvalTilde(path))
 		}
 	}
+	text += policyString()
+	if len(provenance) > 0 {
+		text += "Sources\n" + ProvenanceString()
+	}
 	return text
 }
 
 // autoCfgEnv string
 func autoCfgEnv() (v string) {
 	defer Trace.ScopedTrace()()
-	for _, e := range os.Environ() {
+	for _, e := range Environ() {
 		key, value, found := strings.Cut(e, "=")
 		v += fmt.Sprintf("%s: %s %t\n", key, value, found)
 	}
 	return
 }
 
-// ExpandEnvEvalTilde expand ${var} and ~/
+// ExpandEnvEvalTilde interpolate ${var} and expand ~/, a path that
+// fails to expand is warned and returned empty so searches skip it
 func ExpandEnvEvalTilde(path string) string {
 	defer Trace.ScopedTrace()()
 	var err error
-	if path, err = homedir.Expand(os.ExpandEnv(path)); err != nil {
-		fmt.Printf("homedir problem %v\n", err)
+	var text string
+	if text, err = Interpolate(path); err != nil {
+		warn("search path %s skipped: %v", path, err)
+		return ""
 	}
-	return path
+	if text, err = homedir.Expand(text); err != nil {
+		warn("search path %s skipped: %v", path, err)
+		return ""
+	}
+	return text
 }
 
 // Reset flags for reconfigure
 func Reset() {
 	defer Trace.ScopedTrace()()
 	cfg.Reset(pgm)
+	ResetEnv()
+	ResetProvenance()
+	ResetPolicy()
+	ResetWarnings()
+	hidden = map[string]bool{}
+	aliasFlags = map[string]aliasFlag{}
+	checkConfig = false
+	configFile, configMode, writeConfig = "", "", ""
+	printConfig, printSearchPaths = false, false
+	completionShell = ""
+	flagErr = nil
 }
@@ -58,7 +58,7 @@ func TestAutoCfgVar(t *testing.T) {
 // }
 
 func TestGenerator(t *testing.T) {
-  Generator(&fakeTestConf{VaultAddr: "https://vault", Role: "abc123...", Secret: "def456..."})
+  Generator(&fakeTestConf{VaultAddr: "https://vault", Role: "abc123...", Secret: "def456..."}, true)
 }
 
 func TestConfigure(t *testing.T) {
@@ -0,0 +1,244 @@
+package autocfg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+
+	eflag "github.com/davidwalter0/go-flag"
+)
+
+/*
+StandardFlags adds the standard flags to Configure and the multicall
+variants
+
+	--config FILE           load FILE first, like AUTOCFG_FILENAME
+	--config-mode MODE      first or union search mode
+	--print-config          print the redacted configuration and exit
+	--print-search-paths    print the search paths and exit
+	--write-config FILE     write the configuration to FILE and exit
+
+A flag defined by a field of the same name takes precedence.
+*/
+var StandardFlags bool
+
+// standard flag names
+const (
+	ConfigFlag           = "config"
+	ConfigModeFlag       = "config-mode"
+	PrintConfigFlag      = "print-config"
+	PrintSearchPathsFlag = "print-search-paths"
+	WriteConfigFlag      = "write-config"
+)
+
+// PrintOutput receives the --print-config and --print-search-paths
+// output
+var PrintOutput io.Writer = os.Stdout
+
+// SecretTag marks a field redacted in printed configuration,
+// `secret:"true"`
+const SecretTag = "secret"
+
+// SecretNames matches the json names of fields redacted without a
+// secret tag, nil to redact tagged fields only
+var SecretNames = regexp.MustCompile(`(?i)(password|passwd|secret|token|credential|private-?key)`)
+
+// Redacted replaces the value of secret fields
+const Redacted = "REDACTED"
+
+var (
+	configFile       string
+	configMode       string
+	printConfig      bool
+	printSearchPaths bool
+	writeConfig      string
+)
+
+// isSecret reports whether the field is redacted
+func isSecret(sf reflect.StructField) bool {
+	if text, ok := sf.Tag.Lookup(SecretTag); ok {
+		return text == "true"
+	}
+	return SecretNames != nil && SecretNames.MatchString(jsonName(sf))
+}
+
+// Redact returns obj as a json tree with the non empty values of
+// secret fields, by SecretTag or SecretNames, replaced by Redacted
+func Redact(obj any) (tree map[string]any, err error) {
+	defer Trace.ScopedTrace()()
+	var text []byte
+	if text, err = json.Marshal(obj); err != nil {
+		return
+	}
+	var ok bool
+	if tree, ok = decodeTree(text).(map[string]any); !ok {
+		return nil, fmt.Errorf("arg obj any [%T]: object is not a pointer to struct", obj)
+	}
+	for _, l := range leaves(obj) {
+		if isSecret(l.Field) && !l.Value.IsZero() {
+			redactPath(tree, l.Path)
+		}
+	}
+	return
+}
+
+// redactPath replaces the value at path when present
+func redactPath(tree map[string]any, path []string) {
+	for _, key := range path[:len(path)-1] {
+		var ok bool
+		if tree, ok = tree[key].(map[string]any); !ok {
+			return
+		}
+	}
+	if _, ok := tree[path[len(path)-1]]; ok {
+		tree[path[len(path)-1]] = Redacted
+	}
+}
+
+// scanStandardFlags applies --config and --config-mode before the
+// configuration files load, the flags are parsed later with the rest.
+// The arguments are scanned as the flag parser reads them: -name or
+// --name, the value after = or in the next argument for flags taking
+// one, stopping at -- or the first argument not a flag.
+func scanStandardFlags(obj any) (err error) {
+	if !StandardFlags {
+		return
+	}
+	var fields = map[string]bool{}
+	var takesValue = map[string]bool{
+		ConfigFlag:      true,
+		ConfigModeFlag:  true,
+		WriteConfigFlag: true,
+		CompletionFlag:  true,
+	}
+	for _, l := range leaves(obj) {
+		var value = l.Field.Type.Kind() != reflect.Bool
+		fields[cfgFlagName(l.Path)] = true
+		for _, name := range append([]string{cfgFlagName(l.Path), cfgFlagName(l.Path[len(l.Path)-1:])}, aliases(l.Field)...) {
+			takesValue[name] = value
+		}
+	}
+	var args = os.Args[1:]
+	for len(args) > 0 {
+		var arg = args[0]
+		if len(arg) < 2 || arg[0] != '-' || arg == "--" {
+			return
+		}
+		var name = strings.TrimPrefix(arg[1:], "-")
+		if len(name) == 0 || name[0] == '-' || name[0] == '=' {
+			return
+		}
+		args = args[1:]
+		var value string
+		var ok bool
+		name, value, ok = strings.Cut(name, "=")
+		if !ok && takesValue[name] {
+			if len(args) == 0 {
+				return
+			}
+			value, args = args[0], args[1:]
+		}
+		switch {
+		case fields[name]:
+		case name == ConfigFlag:
+			configFile = value
+			err = errors.Join(err, Setenv("AUTOCFG_FILENAME", value, false))
+		case name == ConfigModeFlag:
+			configMode = value
+			err = errors.Join(err, applyConfigMode(value))
+		}
+	}
+	return
+}
+
+// applyConfigMode replaces the First or Union part of the search mode
+func applyConfigMode(text string) (err error) {
+	var part SearchMode
+	switch strings.ToLower(text) {
+	case "first":
+		part = First
+	case "union":
+		part = Union
+	default:
+		return fmt.Errorf("--%s %q: expected first or union", ConfigModeFlag, text)
+	}
+	_, err = SetMode(mode&^(First|Union) | part)
+	return
+}
+
+// defineStandardFlags adds the standard flags not already defined
+func defineStandardFlags() {
+	if !StandardFlags {
+		return
+	}
+	var define = func(name string, add func()) {
+		if eflag.Lookup(name) == nil {
+			add()
+		}
+	}
+	define(ConfigFlag, func() {
+		eflag.CommandLine.StringVar(&configFile, ConfigFlag, configFile,
+			"configuration file loaded first, like AUTOCFG_FILENAME", false, false)
+	})
+	define(ConfigModeFlag, func() {
+		eflag.CommandLine.StringVar(&configMode, ConfigModeFlag, configMode,
+			"configuration search mode, first or union", false, false)
+	})
+	define(PrintConfigFlag, func() {
+		eflag.CommandLine.BoolVar(&printConfig, PrintConfigFlag, false,
+			"print the configuration, secrets redacted, then exit", false, false)
+	})
+	define(PrintSearchPathsFlag, func() {
+		eflag.CommandLine.BoolVar(&printSearchPaths, PrintSearchPathsFlag, false,
+			"print the configuration search paths then exit", false, false)
+	})
+	define(WriteConfigFlag, func() {
+		eflag.CommandLine.StringVar(&writeConfig, WriteConfigFlag, "",
+			"write the configuration to a new file then exit", false, false)
+	})
+}
+
+// runStandardFlags prints or writes the configuration of obj and
+// exits when a standard flag asks to
+func runStandardFlags(obj any) {
+	var err error
+	switch {
+	case printSearchPaths:
+		fmt.Fprint(PrintOutput, String())
+	case printConfig:
+		var tree map[string]any
+		if tree, err = Redact(obj); err == nil {
+			var text, _ = json.MarshalIndent(tree, "", "  ")
+			fmt.Fprintf(PrintOutput, "%s\n", text)
+		}
+	case len(writeConfig) > 0:
+		err = writeConfigFile(writeConfig, obj)
+	default:
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(1)
+		return
+	}
+	exit(0)
+}
+
+// writeConfigFile writes the configuration template of obj to a new
+// file readable by its owner only, it may hold secrets
+func writeConfigFile(path string, obj any) (err error) {
+	var file *os.File
+	if file, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600); err != nil {
+		return
+	}
+	if err = WriteTemplate(file, obj, TemplateFormat(path)); err != nil {
+		file.Close()
+		return
+	}
+	return file.Close()
+}
@@ -0,0 +1,103 @@
+package autocfg
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type builtinConf struct {
+	Role     string `json:"role"`
+	Secret   string `json:"secret"`
+	Password string `json:"pass" secret:"true"`
+	Port     int    `json:"port"`
+}
+
+func TestStandardFlags(t *testing.T) {
+	var args, output, saved = os.Args, PrintOutput, GetMode()
+	defer func() {
+		os.Args, PrintOutput, exit = args, output, os.Exit
+		StandardFlags = false
+		_, _ = SetMode(saved)
+		Reset()
+	}()
+	Reset()
+	StandardFlags = true
+	_, _ = SetMode(Direct | First)
+	var buffer bytes.Buffer
+	PrintOutput = &buffer
+	var code = -1
+	exit = func(c int) { code = c }
+	var dir = t.TempDir()
+	var path = filepath.Join(dir, "app.json")
+	if err := os.WriteFile(path, []byte(`{"role": "reader", "secret": "s3", "pass": "pw"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Args = []string{"autocfg.test", "--config", path, "--config-mode=union", "--print-config", "--port", "8200"}
+	Configure(&builtinConf{})
+	if code != 0 || GetMode()&Union != Union {
+		t.Fatalf("expected exit 0 in union mode got %d %s", code, Mode())
+	}
+	var printed map[string]any
+	if err := json.Unmarshal(buffer.Bytes(), &printed); err != nil {
+		t.Fatalf("%v\n%s", err, buffer.String())
+	}
+	if printed["role"] != "reader" || printed["secret"] != Redacted || printed["pass"] != Redacted || printed["port"] != float64(8200) {
+		t.Errorf("unexpected printed config %v", printed)
+	}
+
+	Reset()
+	code = -1
+	var written = filepath.Join(dir, "written.json")
+	os.Args = []string{"autocfg.test", "-config", path, "-write-config", written}
+	Configure(&builtinConf{})
+	var text, err = os.ReadFile(written)
+	if code != 0 || err != nil {
+		t.Fatalf("expected %s written got exit %d %v", written, code, err)
+	}
+	var conf builtinConf
+	if err = json.Unmarshal(text, &conf); err != nil || conf.Secret != "s3" {
+		t.Errorf("expected the written config unredacted got %+v %v", conf, err)
+	}
+	Reset()
+	code = -1
+	os.Args = []string{"autocfg.test", "-config", path, "-write-config", written}
+	Configure(&builtinConf{})
+	if code != 1 {
+		t.Errorf("expected exit 1 writing over %s got %d", written, code)
+	}
+}
+
+func TestScanStandardFlags(t *testing.T) {
+	var args = os.Args
+	defer func() {
+		os.Args = args
+		StandardFlags = false
+		Reset()
+	}()
+	StandardFlags = true
+	for line, expect := range map[string]string{
+		"-config=a.json":           "a.json",
+		"--config a.json":          "a.json",
+		"--role r -config a.json":  "a.json",
+		"--pass=x --config a.json": "a.json",
+		"--role --config a.json":   "",
+		"arg --config a.json":      "",
+		"-- --config a.json":       "",
+		"---config a.json":         "",
+		"--config":                 "",
+	} {
+		Reset()
+		configFile = ""
+		os.Args = append([]string{"autocfg.test"}, strings.Fields(line)...)
+		if err := scanStandardFlags(&builtinConf{}); err != nil {
+			t.Fatal(err)
+		}
+		if configFile != expect {
+			t.Errorf("%q expected config %q got %q", line, expect, configFile)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package autocfg
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"reflect"
+
+	eflag "github.com/davidwalter0/go-flag"
+)
+
+// ErrCheckFailed is wrapped by the error of a Check that found errors
+var ErrCheckFailed = errors.New("configuration check failed")
+
+// CheckOutput receives the Check report
+var CheckOutput io.Writer = os.Stdout
+
+// CheckConfigFlag names the flag Configure and the multicall variants
+// add to check the configuration and exit
+var CheckConfigFlag = "check-config"
+
+var checkConfig bool
+
+// exit is os.Exit, replaced in tests
+var exit = os.Exit
+
+/*
+Check loads the named configuration files into obj, or the files of
+the search chain and policy file when no paths are given, over the
+default tag values, then the env layer, and
+validates the result. Unknown keys are errors as with Strict. The
+report lists the errors and warnings of each file, the environment
+and the validation to CheckOutput. The error wraps ErrCheckFailed
+when any are errors. Provenance() reports the sources Check loaded.
+*/
+func Check(obj any, paths ...string) (err error) {
+	defer Trace.ScopedTrace()()
+	if !isPtr(obj) {
+		return fmt.Errorf("arg obj any [%T]: object is not a pointer to struct", obj)
+	}
+	var strict = Strict
+	Strict = true
+	defer func() { Strict = strict }()
+	var named = len(paths) > 0
+	if !named {
+		paths = checkPaths()
+	}
+	ResetProvenance()
+	if err = applyDefaults(obj); err != nil {
+		return
+	}
+	var failed, warned int
+	var section = func(name string, err error, from int) {
+		var errs = flatten(err)
+		var warns = warnings[from:]
+		failed += len(errs)
+		warned += len(warns)
+		if len(errs) == 0 && len(warns) == 0 {
+			fmt.Fprintf(CheckOutput, "%s: ok\n", name)
+			return
+		}
+		fmt.Fprintf(CheckOutput, "%s:\n", name)
+		for _, e := range errs {
+			fmt.Fprintf(CheckOutput, "  error: %s\n", e)
+		}
+		for _, w := range warns {
+			fmt.Fprintf(CheckOutput, "  warning: %s\n", w)
+		}
+	}
+	var errs []error
+	for _, path := range paths {
+		var from = len(warnings)
+		var lerr error
+		switch path = ExpandEnvEvalTilde(path); {
+		case !exists(path) && named:
+			lerr = fmt.Errorf("%s %w", path, fs.ErrNotExist)
+		case !exists(path):
+			continue
+		case isAutoCfg(path):
+			lerr = LoadIndirect(path, obj)
+		default:
+			lerr = LoadDirect(path, obj)
+		}
+		section(path, lerr, from)
+		errs = append(errs, lerr)
+	}
+	var from = len(warnings)
+	var eerr = checkEnv(obj)
+	section("environment", eerr, from)
+	from = len(warnings)
+	var verr = errors.Join(ResolveReferences(obj), Validate(obj))
+	section("validation", verr, from)
+	errs = append(errs, eerr, verr)
+	if failed > 0 {
+		fmt.Fprintf(CheckOutput, "check failed: %d errors, %d warnings\n", failed, warned)
+		return fmt.Errorf("%w: %w", ErrCheckFailed, errors.Join(errs...))
+	}
+	fmt.Fprintf(CheckOutput, "check passed: %d warnings\n", warned)
+	return
+}
+
+// checkPaths lists the files of the search chain, lowest priority
+// first, as Union mode loads them
+func checkPaths() (paths []string) {
+	if precedence != nil {
+		var direct, indirect = precedenceFiles()
+		return append(indirect, direct...)
+	}
+	var saved = mode
+	mode = mode&^First | Union
+	defer func() { mode = saved }()
+	if Indirect&saved == Indirect {
+		paths = append(paths, IndirectFiles()...)
+	}
+	if Direct&saved == Direct {
+		paths = append(paths, DirectFiles()...)
+	}
+	return append(paths, PolicyFile())
+}
+
+// checkEnv applies the env layer to obj, the policy names when set and
+// the env var names of the flags Configure defined otherwise, falling
+// back to the go-cfg name of the field
+func checkEnv(obj any) (err error) {
+	if envPolicy != nil {
+		return envLayer(obj)
+	}
+	var names = map[string]string{}
+	for name, l := range flagLeaves(obj) {
+		if flag := eflag.Lookup(name); flag != nil && len(flagEnvName(flag)) > 0 {
+			names[l.Key()] = flagEnvName(flag)
+		}
+	}
+	var errs []error
+	for _, l := range leaves(obj) {
+		var name, ok = names[l.Key()]
+		if !ok {
+			name = cfgEnvName(l.Path[len(l.Path)-1:])
+		}
+		var text, set = LookupEnv(name)
+		if !set || len(text) == 0 {
+			continue
+		}
+		if !allowed(l.Field, EnvLayer) {
+			errs = append(errs, notAllowed(l, envSource(name)))
+			continue
+		}
+		if perr := setValue(l.Value, text); perr != nil {
+			errs = append(errs, fmt.Errorf("env %s: %w", name, perr))
+			continue
+		}
+		setSource(l.Key(), envSource(name))
+	}
+	return errors.Join(errs...)
+}
+
+// flatten joined errors to a list
+func flatten(err error) (list []error) {
+	if err == nil {
+		return
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			list = append(list, flatten(e)...)
+		}
+		return
+	}
+	return []error{err}
+}
+
+// defineCheckFlag adds the check config flag unless defined
+func defineCheckFlag() {
+	if len(CheckConfigFlag) == 0 || eflag.Lookup(CheckConfigFlag) != nil {
+		return
+	}
+	eflag.CommandLine.BoolVar(&checkConfig, CheckConfigFlag, false,
+		"check the configuration files, env and values then exit", false, false)
+}
+
+// runCheckFlag checks a fresh copy of obj and exits when the check
+// config flag is set
+func runCheckFlag(obj any) {
+	if !checkConfig {
+		return
+	}
+	if Check(reflect.New(reflect.TypeOf(obj).Elem()).Interface()) != nil {
+		exit(1)
+		return
+	}
+	exit(0)
+}
@@ -0,0 +1,112 @@
+package autocfg
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	eflag "github.com/davidwalter0/go-flag"
+)
+
+type checkConf struct {
+	Role string `json:"role" required:"true"`
+	Port int    `json:"port" default:"8200" min:"1" max:"65535"`
+}
+
+func TestCheck(t *testing.T) {
+	var output = CheckOutput
+	defer func() {
+		CheckOutput = output
+		Reset()
+	}()
+	Reset()
+	var buffer bytes.Buffer
+	CheckOutput = &buffer
+	var dir = t.TempDir()
+	var good = filepath.Join(dir, "good.json")
+	var bad = filepath.Join(dir, "bad.json")
+	if err := os.WriteFile(good, []byte(`{"role": "reader"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(bad, []byte("{\n  \"prot\": 80,\n  \"port\": 0\n}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Check(&checkConf{}, good); err != nil {
+		t.Errorf("expected a passing check got %v\n%s", err, buffer.String())
+	}
+	buffer.Reset()
+	var err = Check(&checkConf{}, good, bad)
+	if !errors.Is(err, ErrCheckFailed) || !errors.Is(err, ErrUnknownKey) || !errors.Is(err, ErrInvalid) {
+		t.Errorf("expected unknown key and invalid errors got %v", err)
+	}
+	for _, want := range []string{
+		good + ": ok",
+		bad + ":\n  error: " + bad + `:2: unknown key "prot", did you mean "port"?`,
+		"validation:\n  error: port: invalid value 0 below minimum 1",
+		"check failed: 2 errors",
+	} {
+		if !strings.Contains(buffer.String(), want) {
+			t.Errorf("expected report to contain\n%s\ngot\n%s", want, buffer.String())
+		}
+	}
+	if Strict {
+		t.Error("Check should restore Strict")
+	}
+}
+
+func TestCheckConfigFlag(t *testing.T) {
+	var args, output = os.Args, CheckOutput
+	defer func() {
+		os.Args, CheckOutput, exit = args, output, os.Exit
+		Reset()
+	}()
+	Reset()
+	CheckOutput = &bytes.Buffer{}
+	var code = -1
+	exit = func(c int) { code = c }
+	os.Args = []string{"autocfg.test", "--check-config"}
+	Configure(&checkConf{})
+	if code != 1 {
+		t.Errorf("expected exit 1 for the missing required role got %d", code)
+	}
+}
+
+type checkPrefixConf struct {
+	Vault struct {
+		Addr string `json:"addr"`
+	} `json:"vault"`
+}
+
+func TestCheckEnvNames(t *testing.T) {
+	var args, output = os.Args, CheckOutput
+	defer func() {
+		os.Args, CheckOutput = args, output
+		Reset()
+	}()
+	Reset()
+	CheckOutput = &bytes.Buffer{}
+	os.Args = []string{"autocfg.test"}
+	if err := PrefixMultiCallConfigure("app", &checkPrefixConf{}); err != nil {
+		t.Fatal(err)
+	}
+	var flag = eflag.Lookup("app-vault-addr")
+	if flag == nil {
+		t.Fatal("expected the prefixed flag app-vault-addr")
+	}
+	var name = flagEnvName(flag)
+	t.Setenv(name, "https://vault:8200")
+	var file = filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(file, []byte(`{}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var o checkPrefixConf
+	if err := Check(&o, file); err != nil {
+		t.Fatal(err)
+	}
+	if o.Vault.Addr != "https://vault:8200" || Provenance()["vault.addr"].Name != name {
+		t.Errorf("expected vault.addr from %s got %+v %v", name, o, Provenance()["vault.addr"])
+	}
+}
@@ -11,11 +11,10 @@ import (
 
 // App config options
 type App struct {
-	VaultAddr   string `json:"vault-address"`
-	Role        string `json:"role"`
-	Secret      string `json:"secret"`
-	AutoCfgFile string `json:"autocfgfile,omitempty" doc:"auto config file name override"`
-	Debug       bool   `json:"debug,omitempty"`
+	VaultAddr string `json:"vault-address" alias:"vault-addr"`
+	Role      string `json:"role"`
+	Secret    string `json:"secret"`
+	Debug     bool   `json:"debug,omitempty"`
 }
 
 var app = &App{}
@@ -26,6 +25,9 @@ var client *api.Client
 func main() {
 	_, _ = autocfg.SetMode(autocfg.Direct | autocfg.Indirect | autocfg.Union)
 	fmt.Println(autocfg.SetMode(autocfg.Direct | autocfg.Union))
+	// --config replaces an AutoCfgFile field, --print-config shows the
+	// secret redacted
+	autocfg.StandardFlags = true
 	autocfg.Configure(app)
 	// autocfg.Dump(app)
 	var err error
@@ -13,7 +13,6 @@ type App struct {
 	VaultAddr string `json:"vault-addr"`
 	Role      string `json:"role"`
 	Secret    string `json:"secret"`
-	Filename  string `json:"filename,omitempty" doc:"filename for command line flag file name override"`
 	Debug     bool   `json:"debug,omitempty"`
 }
 
@@ -26,6 +25,8 @@ func main() {
 	// var text []byte
 	// var err error
 	//  autocfg.Generator(app)
+	// --config FILE, --print-config and the other standard flags
+	autocfg.StandardFlags = true
 	autocfg.SetMode(autocfg.Union | autocfg.Indirect)
 	fmt.Fprintf(os.Stderr, "Mode %v\n", autocfg.SearchModeName(autocfg.GetMode()))
 	if app.Debug {
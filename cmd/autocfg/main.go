@@ -0,0 +1,256 @@
+// autocfg inspects the configuration search of an application: the
+// search paths with their status, the autocfg files and where they
+// point, and the effective configuration merged from the files found.
+// It converts configuration files between formats and formats them.
+//
+//	autocfg paths -app myservice -mode union-direct-indirect
+//	autocfg effective -app myservice -sources
+//	autocfg diff -app myservice -a.name staging -a.env-file staging.env -b.name prod -b.home /srv/prod
+//	autocfg convert config.json config.yaml
+//	autocfg fmt -l /etc/myservice/*.json
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/davidwalter0/go-autocfg"
+)
+
+// command of the autocfg tool, run with its arguments after the
+// command name
+type command struct {
+	Name  string
+	Usage string
+	Run   func(args []string) error
+}
+
+var commands = []command{
+	{"paths", "print the search paths of an application with their status", paths},
+	{"effective", "print the configuration merged from the files found as json", effective},
+	{"diff", "compare the effective configuration in two contexts", diff},
+	{"convert", "convert a configuration file to another format", convert},
+	{"fmt", "format configuration files in place with sorted keys", format},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	for _, c := range commands {
+		if c.Name == os.Args[1] {
+			if err := c.Run(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "autocfg %s: %v\n", c.Name, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	var name = filepath.Base(os.Args[0])
+	fmt.Fprintf(os.Stderr, "Usage: %s command [flags]\n\nCommands:\n", name)
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", c.Name, c.Usage)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun %s command -h for the flags of a command\n", name)
+}
+
+// target flags select the application and search mode to inspect
+type target struct {
+	App  string
+	Mode string
+}
+
+func (t *target) define(flags *flag.FlagSet) {
+	flags.StringVar(&t.App, "app", autocfg.Program(), "application name used in search paths")
+	flags.StringVar(&t.Mode, "mode", "union-direct-indirect", "search mode, e.g. first-direct or union-direct-indirect")
+}
+
+// apply the application name and search mode
+func (t *target) apply() (err error) {
+	if len(strings.TrimSpace(t.App)) == 0 {
+		return fmt.Errorf("-app name is empty")
+	}
+	var m autocfg.SearchMode
+	if m, err = autocfg.ParseSearchMode(t.Mode); err != nil {
+		return
+	}
+	if _, err = autocfg.SetMode(m); err != nil {
+		return
+	}
+	autocfg.SetProgram(t.App)
+	return
+}
+
+func paths(args []string) (err error) {
+	var t target
+	var asJSON bool
+	var flags = flag.NewFlagSet("paths", flag.ExitOnError)
+	t.define(flags)
+	flags.BoolVar(&asJSON, "json", false, "print the status list as json")
+	flags.Parse(args)
+	if err = t.apply(); err != nil {
+		return
+	}
+	var list = autocfg.SearchStatus()
+	if asJSON {
+		return printJSON(list)
+	}
+	fmt.Printf("app %s, search mode %s\n", autocfg.Program(), autocfg.Mode())
+	for _, status := range list {
+		fmt.Println(status)
+	}
+	return
+}
+
+func effective(args []string) (err error) {
+	var t target
+	var sources bool
+	var flags = flag.NewFlagSet("effective", flag.ExitOnError)
+	t.define(flags)
+	flags.BoolVar(&sources, "sources", false, "print the file each value came from with the config")
+	flags.Parse(args)
+	if err = t.apply(); err != nil {
+		return
+	}
+	var config, from, lerr = autocfg.Effective()
+	if sources {
+		err = printJSON(map[string]any{"config": config, "sources": from})
+	} else {
+		err = printJSON(config)
+	}
+	if lerr != nil {
+		return lerr
+	}
+	return
+}
+
+// defineContext adds the flags of a context with prefix, -a.home etc
+func defineContext(flags *flag.FlagSet, prefix string, ctx *autocfg.Context) {
+	ctx.Name = prefix
+	ctx.Env = map[string]string{}
+	flags.StringVar(&ctx.Name, prefix+".name", prefix, "label of context "+prefix)
+	flags.StringVar(&ctx.Home, prefix+".home", "", "home directory of context "+prefix)
+	flags.StringVar(&ctx.Profile, prefix+".profile", "", "dotenv profile of context "+prefix)
+	flags.BoolVar(&ctx.Dotenv, prefix+".dotenv", false, "load the dotenv files of context "+prefix)
+	flags.Func(prefix+".env", "NAME=VALUE env var of context "+prefix+", repeatable", func(text string) error {
+		var key, value, ok = strings.Cut(text, "=")
+		if !ok {
+			return fmt.Errorf("expected NAME=VALUE got %q", text)
+		}
+		ctx.Env[key] = value
+		return nil
+	})
+	flags.Func(prefix+".env-file", "dotenv file of context "+prefix+", repeatable", func(path string) error {
+		ctx.EnvFiles = append(ctx.EnvFiles, path)
+		return nil
+	})
+}
+
+func diff(args []string) (err error) {
+	var t target
+	var a, b autocfg.Context
+	var asJSON bool
+	var flags = flag.NewFlagSet("diff", flag.ExitOnError)
+	t.define(flags)
+	defineContext(flags, "a", &a)
+	defineContext(flags, "b", &b)
+	flags.BoolVar(&asJSON, "json", false, "print the differences as json")
+	flags.Parse(args)
+	if err = t.apply(); err != nil {
+		return
+	}
+	var diffs, derr = autocfg.Diff(nil, a, b)
+	if asJSON {
+		err = printJSON(diffs)
+	} else {
+		for _, d := range diffs {
+			fmt.Printf("%s\n  %s: %s\n  %s: %s\n", d.Key, a.Name, d.A, b.Name, d.B)
+		}
+	}
+	if derr != nil {
+		return derr
+	}
+	return
+}
+
+func convert(args []string) (err error) {
+	var to string
+	var force bool
+	var flags = flag.NewFlagSet("convert", flag.ExitOnError)
+	flags.StringVar(&to, "to", "", "format written to stdout without a dst file: json, jsonc or yaml")
+	flags.BoolVar(&force, "f", false, "replace an existing dst file")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: autocfg convert [flags] src [dst]\n\nFormats follow the file extensions.\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	switch flags.NArg() {
+	case 2:
+		return autocfg.ConvertFile(flags.Arg(0), flags.Arg(1), force)
+	case 1:
+		if len(to) == 0 {
+			return fmt.Errorf("-to format or a dst file is required")
+		}
+		var text, out []byte
+		if text, err = os.ReadFile(flags.Arg(0)); err != nil {
+			return
+		}
+		if out, err = autocfg.Convert(text, autocfg.TemplateFormat(flags.Arg(0)), autocfg.Format(to)); err != nil {
+			return
+		}
+		_, err = os.Stdout.Write(out)
+		return
+	}
+	flags.Usage()
+	os.Exit(2)
+	return
+}
+
+func format(args []string) (err error) {
+	var list, check bool
+	var flags = flag.NewFlagSet("fmt", flag.ExitOnError)
+	flags.BoolVar(&list, "l", false, "list the files formatted")
+	flags.BoolVar(&check, "check", false, "list the files not formatted without writing them, failing when any")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: autocfg fmt [flags] file...\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	var unformatted int
+	for _, path := range flags.Args() {
+		var changed bool
+		if changed, err = autocfg.FormatFile(path, !check); err != nil {
+			return
+		}
+		if changed {
+			unformatted++
+			if list || check {
+				fmt.Println(path)
+			}
+		}
+	}
+	if check && unformatted > 0 {
+		return fmt.Errorf("%d files not formatted", unformatted)
+	}
+	return
+}
+
+func printJSON(v any) (err error) {
+	var encoder = json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
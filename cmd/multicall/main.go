@@ -12,10 +12,9 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"os"
-	"path"
 	"path/filepath"
 	"strings"
 	"time"
@@ -25,105 +24,61 @@ import (
 	"github.com/mitchellh/go-homedir"
 )
 
-// var app = &App{}
-var text []byte
-
 var client *api.Client
 
 func main() {
-	var multicall = &Multicall{}
-	// autocfg.Configure(call)
-	autocfg.SetMode(autocfg.Union | autocfg.Indirect)
-	if false {
-		fmt.Println("autocfg.AutoConfigPath()", autocfg.AutoConfigPath())
-		fmt.Println("autocfg.LocalConfigPath()", autocfg.LocalConfigPath())
-		fmt.Println("autocfg.String()", autocfg.String())
-		autocfg.Verbose(true)
-	}
-	autocfg.Generator(multicall, true)
-	var x = "multicall"
-	switch x {
-	case "unprefix":
-		autocfg.UnprefixedMultiCallConfigure(multicall)
-	case "multicall":
-		autocfg.MultiCallConfigure(multicall)
-	case "prefix":
-		autocfg.PrefixMultiCallConfigure(path.Base(os.Args[0]), multicall)
+	autocfg.SetMode(autocfg.Union | autocfg.Direct | autocfg.Indirect)
+	var multicall = autocfg.NewMulticall("")
+	multicall.Shared = &options
+	multicall.CallFiles = true
+	for _, call := range []autocfg.Call{
+		{Name: "approle", Description: "log in to vault with an approle", Config: &approle, Run: approleLogin},
+		{Name: "github", Description: "log in to vault with a github token", Config: &github, Run: githubLogin},
+		{Name: "token", Description: "look up and renew the vault token", Config: &vault, Section: "vault", Run: tokenLookup},
+	} {
+		Check(multicall.Register(call))
 	}
-	// multicall.Authn("token")
-	// os.Exit(0)
-	// autocfg.Usage(MulticallText())
-	// if true {
-	//  autocfg.Dump(multicall)
-	// }
-	// os.Exit(0)
-	for _, cmd := range []string{ /*"github", "approle",*/ "token"} { //, "github"} {
-		if len(multicall.Github.Token) == 0 && len(multicall.Github.TokenFile) > 0 {
-			var text []byte
-			var err error
-			text, err = EvalFileRead(multicall.Github.TokenFile)
-			if err != nil {
-				Check(err)
-			}
-			if len(text) > 0 {
-				multicall.Github.Token = string(text)
-			}
-		}
-		// if len(multicall.Vault.Token) == 0 && len(multicall.Vault.TokenFile) > 0 {
-		if len(multicall.Vault.TokenFile) > 0 {
-			var text []byte
-			var err error
-			text, err = EvalFileRead(multicall.Vault.TokenFile)
-			if err != nil {
-				Check(err)
-			}
-			if len(text) > 0 {
-				multicall.Vault.Token = string(text)
-			}
+	if err := multicall.Dispatch(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if errors.Is(err, autocfg.ErrUnknownCall) {
+			fmt.Fprint(os.Stderr, MulticallText())
+			os.Exit(2)
 		}
-		// for _, cmd := range []string{"approle", "github", "vault"} {
-		var secret, err = multicall.Authn(cmd)
-		fmt.Printf("secret %+v %s\n", secret, err)
+		os.Exit(1)
 	}
-
-	// autocfg.Configure(&call.Approle)
-	// autocfg.Dump(call.Approle)
-
-	// autocfg.Configure(app)
-	// autocfg.Dump(app)
 }
 
-var Calls = []string{
-	"approle",
-	"github",
-	"token",
-	"help",
-}
-
-type Multicall struct {
-	// Cmd                string `json:"cmd" doc:"multi-call command to run"`
+// Options shared by the calls, the top level of the configuration,
+// each call reads its own section
+//
+//	{"vault-address": "https://vault:8200", "approle": {"role": "reader"}}
+type Options struct {
 	Debug              bool   `json:"debug,omitempty"`
 	InsecureSkipVerify bool   `json:"insecure-skip-verify" default:"true"`
 	VaultAddr          string `json:"vault-address"`
-	Approle            `json:"approle" doc:"Approle authentication"`
-	Github             `json:"github"  doc:"Github authentication"`
-	Vault              Token  `json:"vault" doc:"Vault Token authentication"`
-	Filename           string `json:"filename,omitempty" doc:"filename for command line flag file name override"`
-	// AuthPath           string `json:"auth-path"`
 }
 
+var (
+	options Options
+	approle Approle
+	github  Github
+	vault   Token
+)
+
 // Approle config options
 type Approle struct {
 	Role   string `json:"role"`
 	Secret string `json:"secret"`
-	Mount  string `json:"mount" default:"approle" doc:"typically approle mount is similar to auth/approle/login or auth/approle_{org}/login`
+	Mount  string `json:"mount" default:"approle" doc:"typically approle mount is similar to auth/approle/login or auth/approle_{org}/login"`
+	Login  string `json:"login" default:"auth/${approle.mount}/login" doc:"approle login path"`
 }
 
 // Github config options
 type Github struct {
 	Token     string `json:"token"`
 	TokenFile string `json:"token-file" default:"${HOME}/.secrets/vault-ghe-token"`
-	Mount     string `json:"mount" default:"github_viper-cog" doc:"typically github mount is similar to auth/github/login or auth/github_{org}/login `
+	Mount     string `json:"mount" default:"github_viper-cog" doc:"typically github mount is similar to auth/github/login or auth/github_{org}/login"`
+	Login     string `json:"login" default:"auth/${github.mount}/login" doc:"github login path"`
 }
 
 // Token config options
@@ -179,15 +134,9 @@ func EvalFileRead(filename string) (text []byte, err error) {
 	return
 }
 
-func MulticallText() (text string) {
-	for _, call := range Calls {
-		text += call + "\n"
-	}
-	return fmt.Sprintf(`
-commands:
-
-%s
-
+// MulticallText examples printed after the usage
+func MulticallText() string {
+	return `
 Examples
 
 Extract a token from kube secret in mentos format
@@ -199,111 +148,134 @@ file dot.data.clientToken
 
 env KUBECONFIG=~/.kube/config.osx kubectl --context=qa get -o json secret/vault-token | jq -r '.data."vault-token"|=@base64d|.data."vault-token"'|jq -c | tee dot.data.clientToken|jq -r .clientToken| tr -d '\n' 
 
-`, text)
+`
 }
 
-func (app *Multicall) Authn(cmd string) (secret *api.Secret, err error) {
-	// if len(os.Args) == 1 {
-	//  autocfg.Usage(MulticallText())
-	//  return
-	// }
-	var filename = strings.TrimSpace(Abs(os.ExpandEnv("~/.vault-token")))
+// newClient of the vault address
+func newClient(options Options) (err error) {
 	var conf = api.DefaultConfig()
-	client, err = api.NewClient(conf)
-	client.SetAddress(app.VaultAddr)
-	switch cmd {
-	case "approle":
-		fmt.Fprintf(os.Stderr, "approle: called as %s %s", path.Base(os.Args[0]), cmd)
-		var mount = fmt.Sprintf("auth/%s/login", app.Approle.Mount)
-		secret, err = client.Logical().Write(mount, map[string]interface{}{
-			"role_id":   app.Role,
-			"secret_id": app.Secret,
-		})
-		if err != nil {
-			log.Fatal(err)
-		}
-		// Use secret.Auth.ClientToken for whatever you want
-		if app.Debug {
-			fmt.Printf("Vault token: %s\n", secret.Auth.ClientToken)
-			fmt.Printf("\nauth: %+v\n", secret)
-		}
-		fmt.Printf("\nToken\n%s\n", secret.Auth.ClientToken)
-		client.SetToken(secret.Auth.ClientToken)
-		err = os.WriteFile(filename, []byte(secret.Auth.ClientToken), 0600)
-	case "github":
-		fmt.Fprintf(os.Stderr, "github: called as %s %s", path.Base(os.Args[0]), cmd)
-		var mount = fmt.Sprintf("auth/%s/login", app.Github.Mount)
-		var options = map[string]interface{}{
-			"token": app.Github.Token,
-		}
-		secret, err = client.Logical().Write(mount, options)
-		if err != nil {
+	if err = conf.ConfigureTLS(&api.TLSConfig{Insecure: options.InsecureSkipVerify}); err != nil {
+		return
+	}
+	if client, err = api.NewClient(conf); err != nil {
+		return
+	}
+	if len(options.VaultAddr) > 0 {
+		err = client.SetAddress(options.VaultAddr)
+	}
+	return
+}
+
+// login writes the login path and saves the client token
+func login(options Options, path string, data map[string]interface{}) (err error) {
+	if err = newClient(options); err != nil {
+		return
+	}
+	var secret *api.Secret
+	if secret, err = client.Logical().Write(path, data); err != nil {
+		return
+	}
+	if secret == nil || secret.Auth == nil {
+		return fmt.Errorf("%s: no auth in response", path)
+	}
+	if options.Debug {
+		fmt.Printf("\nauth: %+v\n", secret)
+	}
+	fmt.Printf("\nToken\n%s\n", secret.Auth.ClientToken)
+	client.SetToken(secret.Auth.ClientToken)
+	var filename = strings.TrimSpace(Abs(os.ExpandEnv("~/.vault-token")))
+	if err = os.WriteFile(filename, []byte(secret.Auth.ClientToken), 0600); err != nil {
+		return
+	}
+	return renew(secret)
+}
+
+func approleLogin(args []string) (err error) {
+	return login(options, approle.Login, map[string]interface{}{
+		"role_id":   approle.Role,
+		"secret_id": approle.Secret,
+	})
+}
+
+func githubLogin(args []string) (err error) {
+	if len(github.Token) == 0 && len(github.TokenFile) > 0 {
+		var text []byte
+		if text, err = EvalFileRead(github.TokenFile); err != nil {
 			return
 		}
-		fmt.Printf("\nToken\n%s\n", secret.Auth.ClientToken)
-		client.SetToken(secret.Auth.ClientToken)
-		err = os.WriteFile(filename, []byte(secret.Auth.ClientToken), 0600)
-	case "token":
-		fmt.Fprintf(os.Stderr, "token: called as %s %s", path.Base(os.Args[0]), cmd)
-		client.SetToken(app.Vault.Token)
-		secret, err = client.Auth().Token().LookupSelf()
-		client.SetToken(app.Vault.Token)
-	case "help":
-		fmt.Fprintf(os.Stderr, "help: called as %s %s", path.Base(os.Args[0]), cmd)
-		autocfg.Usage(MulticallText())
-	default:
-		autocfg.Usage(MulticallText())
+		github.Token = strings.TrimSpace(string(text))
 	}
-	if secret != nil {
+	return login(options, github.Login, map[string]interface{}{
+		"token": github.Token,
+	})
+}
+
+func tokenLookup(args []string) (err error) {
+	if len(vault.TokenFile) > 0 {
 		var text []byte
-		text, err = json.MarshalIndent(secret.Data, "", "  ")
-		fmt.Printf("\nToken\n%s\n", string(text))
-		var timeLeft time.Duration
-		timeLeft, err = secret.TokenTTL()
-		if err != nil {
-			err = fmt.Errorf("Vault: unable to lookup token details: %v", err)
+		if text, err = EvalFileRead(vault.TokenFile); err != nil {
 			return
 		}
-		var isRenewable bool
-		isRenewable, err = secret.TokenIsRenewable()
+		vault.Token = strings.TrimSpace(string(text))
+	}
+	if err = newClient(options); err != nil {
+		return
+	}
+	client.SetToken(vault.Token)
+	var secret *api.Secret
+	if secret, err = client.Auth().Token().LookupSelf(); err != nil {
+		return
+	}
+	return renew(secret)
+}
+
+// renew the token of secret when inside the refresh window
+func renew(secret *api.Secret) (err error) {
+	if secret == nil {
+		return
+	}
+	var text []byte
+	text, err = json.MarshalIndent(secret.Data, "", "  ")
+	fmt.Printf("\nToken\n%s\n", string(text))
+	var timeLeft time.Duration
+	timeLeft, err = secret.TokenTTL()
+	if err != nil {
+		err = fmt.Errorf("Vault: unable to lookup token details: %v", err)
+		return
+	}
+	var isRenewable bool
+	isRenewable, err = secret.TokenIsRenewable()
+	if err != nil {
+		err = fmt.Errorf("Vault: unable to lookup TokenIsRenewable: %v", err)
+		return
+	}
+	if secret.Data != nil && secret.Data["period"] != nil {
+		var rw int64
+		rw, err = secret.Data["period"].(json.Number).Int64()
 		if err != nil {
-			err = fmt.Errorf("Vault: unable to lookup TokenIsRenewable: %v", err)
-			return
-		}
-		if secret != nil && secret.Data != nil && secret.Data["period"] != nil {
-			fmt.Fprintf(os.Stderr, "type %T %+v\n", secret.Data["period"], secret.Data["period"])
-			var rw int64
-			rw, err = secret.Data["period"].(json.Number).Int64()
-			if err != nil {
-				err = fmt.Errorf("Vault: unable to find period: %v", err)
-			} else {
-				refreshWindow = rw
-			}
-			fmt.Fprintf(os.Stderr, "type %T %+v %v\n", rw, rw, err)
+			err = fmt.Errorf("Vault: unable to find period: %v", err)
+		} else {
+			refreshWindow = rw
 		}
+	}
 
-		if timeLeft.Seconds() < float64(refreshWindow) && !isRenewable {
-			err = fmt.Errorf("Vault expired not renewable: %v", err)
-			return
-		}
+	if timeLeft.Seconds() < float64(refreshWindow) && !isRenewable {
+		err = fmt.Errorf("Vault expired not renewable: %v", err)
+		return
+	}
 
-		if timeLeft.Seconds() < float64(refreshWindow) {
-			//      os.Setenv("VAULT_TOKEN", app.Vault.Token)
-			// secret, err = client.Auth().Token().Renew(app.Vault.Token, refreshWindow)
-			secret, err = client.Auth().Token().RenewSelf(0)
-			// secret, err = client.Auth().Token().RenewSelf(refreshWindow)
-			if err != nil {
-				err = fmt.Errorf("Vault unable to renew vault token: %v", err)
-				return
-			}
-		}
-		secret, err = client.Auth().Token().LookupSelf()
-		if secret != nil {
-			text, err = json.MarshalIndent(secret.Data, "", "  ")
-			fmt.Printf("\nToken\n%s\n", string(text))
+	if timeLeft.Seconds() < float64(refreshWindow) {
+		secret, err = client.Auth().Token().RenewSelf(0)
+		if err != nil {
+			err = fmt.Errorf("Vault unable to renew vault token: %v", err)
+			return
 		}
 	}
-
+	secret, err = client.Auth().Token().LookupSelf()
+	if secret != nil {
+		text, err = json.MarshalIndent(secret.Data, "", "  ")
+		fmt.Printf("\nToken\n%s\n", string(text))
+	}
 	return
 }
 
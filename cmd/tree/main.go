@@ -0,0 +1,90 @@
+// tree is an example command tree with inherited options, each
+// command prints the configuration it sees
+//
+//	tree --vault-address https://vault:8200 vault login approle --role reader
+//	tree vault token --debug
+//	tree vault login -h
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/davidwalter0/go-autocfg"
+)
+
+// Tool options are inherited by every command
+type Tool struct {
+	Debug     bool   `json:"debug,omitempty" doc:"print debug output"`
+	VaultAddr string `json:"vault-address" doc:"vault server address"`
+	Vault     Vault  `json:"vault" cmd:"vault" doc:"vault commands"`
+}
+
+// Vault commands
+type Vault struct {
+	Namespace string `json:"namespace" doc:"vault namespace"`
+	Login     Login  `json:"login" cmd:"login" doc:"log in to vault"`
+	Token     Token  `json:"token" cmd:"token" doc:"look up the vault token"`
+}
+
+// Login commands
+type Login struct {
+	Approle Approle `json:"approle" cmd:"approle" doc:"log in with an approle"`
+	Github  Github  `json:"github" cmd:"github" doc:"log in with a github token"`
+}
+
+// Approle login options
+type Approle struct {
+	Role   string `json:"role"`
+	Secret string `json:"secret"`
+	Mount  string `json:"mount" default:"approle"`
+	Login  string `json:"login" default:"auth/${vault.login.approle.mount}/login" doc:"approle login path"`
+}
+
+// Github login options
+type Github struct {
+	Token     string `json:"token"`
+	TokenFile string `json:"token-file" default:"${HOME}/.secrets/vault-ghe-token"`
+	Mount     string `json:"mount" default:"github"`
+}
+
+// Token lookup options
+type Token struct {
+	TokenFile string `json:"token-file" default:"${HOME}/.vault-token"`
+}
+
+var tool Tool
+
+// Run the approle login
+func (c *Approle) Run(args []string) error { return show("vault login approle", c) }
+
+// Run the github login
+func (c *Github) Run(args []string) error { return show("vault login github", c) }
+
+// Run the token lookup
+func (c *Token) Run(args []string) error { return show("vault token", c) }
+
+// show the inherited options with the options of a command
+func show(name string, command any) error {
+	var text, err = json.MarshalIndent(map[string]any{
+		"debug":         tool.Debug,
+		"vault-address": tool.VaultAddr,
+		"namespace":     tool.Vault.Namespace,
+		name:            command,
+	}, "", "  ")
+	fmt.Println(string(text))
+	return err
+}
+
+func main() {
+	autocfg.SetMode(autocfg.Union | autocfg.Direct | autocfg.Indirect)
+	if err := autocfg.DispatchTree(&tool, os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if errors.Is(err, autocfg.ErrUnknownCall) {
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,222 @@
+package autocfg
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	eflag "github.com/davidwalter0/go-flag"
+)
+
+// CompletionFlag names the flag Configure and the multicall variants
+// add to print a shell completion script and exit
+var CompletionFlag = "completion"
+
+// CompleteTag hints the completion of a flag value, `complete:"file"`
+// or `complete:"dir"`. Fields named like *-file, *-path or *-dir get
+// the hint without the tag.
+const CompleteTag = "complete"
+
+// Shells with completion scripts
+var Shells = []string{"bash", "zsh", "fish"}
+
+var completionShell string
+
+// completion of a flag
+type completion struct {
+	Name   string
+	Usage  string
+	Bool   bool
+	Values []string
+	// Hint is file, dir or empty for any text
+	Hint string
+}
+
+var pathName = regexp.MustCompile(`(^|-)(file|path)$`)
+var dirName = regexp.MustCompile(`(^|-)dir$`)
+
+// completions lists the visible flags, with the enum values and path
+// hints of the fields of obj and of the standard flags
+func completions(obj any) (list []completion) {
+	var fields = flagLeaves(obj)
+	eflag.VisitAll(func(flag *eflag.Flag) {
+		if hidden[flag.Name] {
+			return
+		}
+		var c = completion{Name: flag.Name, Usage: firstLine(flag.Usage)}
+		if b, ok := flag.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			c.Bool = true
+		}
+		switch flag.Name {
+		case ConfigFlag, WriteConfigFlag:
+			c.Hint = "file"
+		case ConfigModeFlag:
+			c.Values = []string{"first", "union"}
+		case CompletionFlag:
+			c.Values = Shells
+		}
+		if l, ok := fields[flag.Name]; ok {
+			var sf = l.Field
+			// the go-cfg usage repeats the name and type
+			c.Usage = firstLine(docText(sf))
+			c.Bool = c.Bool || sf.Type.Kind() == reflect.Bool
+			if enum, err := constraints(sf); err == nil {
+				c.Values = enum.Enum
+			}
+			c.Hint = sf.Tag.Get(CompleteTag)
+		}
+		if len(c.Hint) == 0 && len(c.Values) == 0 && !c.Bool {
+			switch {
+			case pathName.MatchString(flag.Name):
+				c.Hint = "file"
+			case dirName.MatchString(flag.Name):
+				c.Hint = "dir"
+			}
+		}
+		list = append(list, c)
+	})
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return
+}
+
+func firstLine(text string) string {
+	var line, _, _ = strings.Cut(strings.TrimSpace(text), "\n")
+	return strings.TrimSpace(line)
+}
+
+/*
+Completion writes a bash, zsh or fish completion script for the flags
+of the program, as defined by Configure or a multicall variant for
+obj, nested and prefixed flag names included. Enum tag values complete
+flag values, and complete tags or file, path and dir names complete
+paths.
+
+	myapp --completion bash > /etc/bash_completion.d/myapp
+*/
+func Completion(w io.Writer, shell string, obj any) (err error) {
+	defer Trace.ScopedTrace()()
+	var list = completions(obj)
+	var buffer bytes.Buffer
+	switch shell {
+	case "bash":
+		writeBashCompletion(&buffer, pgm, list)
+	case "zsh":
+		writeZshCompletion(&buffer, pgm, list)
+	case "fish":
+		writeFishCompletion(&buffer, pgm, list)
+	default:
+		return fmt.Errorf("completion for shell %q, expected one of %s", shell, strings.Join(Shells, ", "))
+	}
+	_, err = w.Write(buffer.Bytes())
+	return
+}
+
+var notIdent = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+func writeBashCompletion(w *bytes.Buffer, program string, list []completion) {
+	var function = "_" + notIdent.ReplaceAllString(program, "_") + "_complete"
+	var names []string
+	fmt.Fprintf(w, "# bash completion for %s\n", program)
+	fmt.Fprintf(w, "%s() {\n", function)
+	w.WriteString("\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	w.WriteString("\tcase \"$prev\" in\n")
+	for _, c := range list {
+		names = append(names, "--"+c.Name)
+		if c.Bool {
+			continue
+		}
+		var reply = "COMPREPLY=()"
+		switch {
+		case len(c.Values) > 0:
+			reply = fmt.Sprintf("COMPREPLY=($(compgen -W %q -- \"$cur\"))", strings.Join(c.Values, " "))
+		case c.Hint == "file":
+			reply = "COMPREPLY=($(compgen -f -- \"$cur\"))"
+		case c.Hint == "dir":
+			reply = "COMPREPLY=($(compgen -d -- \"$cur\"))"
+		}
+		fmt.Fprintf(w, "\t-%s|--%s)\n\t\t%s\n\t\treturn\n\t\t;;\n", c.Name, c.Name, reply)
+	}
+	w.WriteString("\tesac\n")
+	fmt.Fprintf(w, "\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(names, " "))
+	w.WriteString("}\n")
+	fmt.Fprintf(w, "complete -o filenames -F %s %s\n", function, program)
+}
+
+// zshQuote escapes text for a zsh _arguments spec in single quotes
+var zshQuote = strings.NewReplacer("'", `'\''`, "[", `\[`, "]", `\]`, ":", `\:`)
+
+func writeZshCompletion(w *bytes.Buffer, program string, list []completion) {
+	fmt.Fprintf(w, "#compdef %s\n\n_arguments \\\n", program)
+	for i, c := range list {
+		var spec = fmt.Sprintf("--%s[%s]", c.Name, zshQuote.Replace(c.Usage))
+		switch {
+		case c.Bool:
+		case len(c.Values) > 0:
+			spec += fmt.Sprintf(":%s:(%s)", c.Name, zshQuote.Replace(strings.Join(c.Values, " ")))
+		case c.Hint == "file":
+			spec += ":file:_files"
+		case c.Hint == "dir":
+			spec += ":directory:_files -/"
+		default:
+			spec += ":" + c.Name + ": "
+		}
+		fmt.Fprintf(w, "  '%s'", spec)
+		if i < len(list)-1 {
+			w.WriteString(" \\")
+		}
+		w.WriteString("\n")
+	}
+}
+
+// fishQuote escapes text in fish single quotes
+var fishQuote = strings.NewReplacer(`\`, `\\`, "'", `\'`)
+
+func writeFishCompletion(w *bytes.Buffer, program string, list []completion) {
+	fmt.Fprintf(w, "# fish completion for %s\n", program)
+	for _, c := range list {
+		var line = fmt.Sprintf("complete -c %s -l %s", program, c.Name)
+		if len(c.Usage) > 0 {
+			line += fmt.Sprintf(" -d '%s'", fishQuote.Replace(c.Usage))
+		}
+		switch {
+		case c.Bool:
+		case len(c.Values) > 0:
+			line += fmt.Sprintf(" -x -a '%s'", fishQuote.Replace(strings.Join(c.Values, " ")))
+		case c.Hint == "file":
+			line += " -r -F"
+		case c.Hint == "dir":
+			line += " -x -a '(__fish_complete_directories)'"
+		default:
+			line += " -x"
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+// defineCompletionFlag adds the completion flag unless defined
+func defineCompletionFlag() {
+	if len(CompletionFlag) == 0 || eflag.Lookup(CompletionFlag) != nil {
+		return
+	}
+	eflag.CommandLine.StringVar(&completionShell, CompletionFlag, "",
+		"print a "+strings.Join(Shells, ", ")+" completion script then exit", false, false)
+}
+
+// runCompletionFlag prints the completion script for obj and exits
+// when the completion flag is set
+func runCompletionFlag(obj any) {
+	if len(completionShell) == 0 {
+		return
+	}
+	if err := Completion(PrintOutput, completionShell, obj); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(2)
+		return
+	}
+	exit(0)
+}
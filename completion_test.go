@@ -0,0 +1,60 @@
+package autocfg
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+type completionConf struct {
+	Level string `json:"level" enum:"debug,info,warn" doc:"log level"`
+	Debug bool   `json:"debug"`
+	Vault struct {
+		Addr     string `json:"addr" doc:"vault address"`
+		CertFile string `json:"cert-file"`
+		Data     string `json:"data" complete:"dir"`
+	} `json:"vault"`
+}
+
+func TestCompletion(t *testing.T) {
+	var args, output = os.Args, PrintOutput
+	defer func() {
+		os.Args, PrintOutput, exit = args, output, os.Exit
+		Reset()
+	}()
+	Reset()
+	var buffer bytes.Buffer
+	PrintOutput = &buffer
+	var code = -1
+	exit = func(c int) { code = c }
+	os.Args = []string{"autocfg.test", "--completion", "fish"}
+	Configure(&completionConf{})
+	if code != 0 {
+		t.Fatalf("expected exit 0 got %d", code)
+	}
+	for _, want := range []string{
+		" -l level -d 'log level' -x -a 'debug info warn'\n",
+		" -l debug\n",
+		" -l addr -d 'vault address' -x\n",
+		" -l cert-file -r -F\n",
+		" -l data -x -a '(__fish_complete_directories)'\n",
+		" -l completion -d 'print a bash, zsh, fish completion script then exit' -x -a 'bash zsh fish'\n",
+	} {
+		if !strings.Contains(buffer.String(), "complete -c "+Program()+want) {
+			t.Errorf("expected %q in\n%s", want, buffer.String())
+		}
+	}
+	for _, shell := range []string{"bash", "zsh"} {
+		buffer.Reset()
+		if err := Completion(&buffer, shell, &completionConf{}); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buffer.String(), "--cert-file") {
+			t.Errorf("expected --cert-file in the %s script\n%s", shell, buffer.String())
+		}
+	}
+	if err := Completion(&buffer, "csh", &completionConf{}); err == nil {
+		t.Error("expected an unknown shell error")
+	}
+}
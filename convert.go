@@ -0,0 +1,442 @@
+package autocfg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrUnsupportedFormat is returned reading a format without a reader
+// and converting to one the loaders do not read
+var ErrUnsupportedFormat = errors.New("unsupported format")
+
+/*
+Convert configuration text from one format to another. Keys keep
+their order in the file and comments above a key are carried to
+formats with comments. JSON, JSONC and YAML are read and written, the
+formats LoadDirect reads. Comments inside arrays are dropped.
+*/
+func Convert(text []byte, from, to Format) (out []byte, err error) {
+	defer Trace.ScopedTrace()()
+	var nodes []node
+	if nodes, err = readNodes("", text, from); err != nil {
+		return
+	}
+	return writeNodes(nodes, to)
+}
+
+// Canonical formats configuration text in its format with sorted keys
+// and two space indentation, keeping comments
+func Canonical(text []byte, format Format) (out []byte, err error) {
+	defer Trace.ScopedTrace()()
+	var nodes []node
+	if nodes, err = readNodes("", text, format); err != nil {
+		return
+	}
+	sortNodes(nodes)
+	return writeNodes(nodes, format)
+}
+
+// ConvertFile converts the file src to dst in the TemplateFormat of
+// each path. An existing dst is replaced only when overwrite is set.
+func ConvertFile(src, dst string, overwrite bool) (err error) {
+	defer Trace.ScopedTrace()()
+	if _, err = os.Stat(dst); err == nil && !overwrite {
+		return fmt.Errorf("%s %w", dst, fs.ErrExist)
+	}
+	var text, out []byte
+	if text, err = os.ReadFile(src); err != nil {
+		return
+	}
+	var nodes []node
+	if nodes, err = readNodes(src, text, TemplateFormat(src)); err != nil {
+		return
+	}
+	if out, err = writeNodes(nodes, TemplateFormat(dst)); err != nil {
+		return
+	}
+	return os.WriteFile(dst, out, 0644)
+}
+
+// FormatFile compares the file at path to its Canonical form, changed
+// reports whether they differ and the file is rewritten when write is
+// set. Comments in a .json file read as JSONC are kept.
+func FormatFile(path string, write bool) (changed bool, err error) {
+	defer Trace.ScopedTrace()()
+	var text, out []byte
+	if text, err = os.ReadFile(path); err != nil {
+		return
+	}
+	var format = TemplateFormat(path)
+	var nodes []node
+	if nodes, err = readNodes(path, text, format); err != nil {
+		return
+	}
+	if format == FormatJSON && documented(nodes) {
+		format = FormatJSONC
+	}
+	sortNodes(nodes)
+	if out, err = writeNodes(nodes, format); err != nil {
+		return
+	}
+	if changed = !bytes.Equal(text, out); !changed || !write {
+		return
+	}
+	var info fs.FileInfo
+	if info, err = os.Stat(path); err != nil {
+		return
+	}
+	return true, os.WriteFile(path, out, info.Mode().Perm())
+}
+
+// documented reports whether any node has a doc comment
+func documented(nodes []node) bool {
+	for _, n := range nodes {
+		if len(n.Doc) > 0 || documented(n.Children) {
+			return true
+		}
+	}
+	return false
+}
+
+// readNodes decodes an object in format to nodes in file order
+func readNodes(path string, text []byte, format Format) (nodes []node, err error) {
+	switch format {
+	case FormatJSON, FormatJSONC:
+		return readJSONNodes(path, text)
+	case FormatYAML:
+		return readYAMLNodes(path, text)
+	}
+	return nil, fmt.Errorf("%s reading %s: %w", path, format, ErrUnsupportedFormat)
+}
+
+// writeNodes encodes nodes in format
+func writeNodes(nodes []node, format Format) (out []byte, err error) {
+	var buffer bytes.Buffer
+	switch format {
+	case FormatJSON, FormatJSONC:
+		buffer.WriteString("{\n")
+		writeJSONC(&buffer, nodes, 1, format == FormatJSONC)
+		buffer.WriteString("}\n")
+	case FormatYAML:
+		writeYAML(&buffer, nodes, 0)
+	default:
+		// toml is left out, the loaders do not read it
+		return nil, fmt.Errorf("writing %q: %w", format, ErrUnsupportedFormat)
+	}
+	return buffer.Bytes(), nil
+}
+
+// sortNodes by key at every depth
+func sortNodes(nodes []node) {
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].Key < nodes[j].Key })
+	for _, n := range nodes {
+		sortNodes(n.Children)
+	}
+}
+
+// jsonReader reads JSONC tokens from the standardized text, finding
+// comments between tokens in the source
+type jsonReader struct {
+	path     string
+	source   []byte
+	standard []byte
+	origin   func(int64) int64
+	decoder  *json.Decoder
+}
+
+// readJSONNodes decodes a json object to nodes, JSONC is accepted
+func readJSONNodes(path string, text []byte) (nodes []node, err error) {
+	var standard, origin = standardize(text)
+	var r = &jsonReader{path: path, source: text, standard: standard, origin: origin,
+		decoder: json.NewDecoder(bytes.NewReader(standard))}
+	r.decoder.UseNumber()
+	var token json.Token
+	if token, err = r.decoder.Token(); err != nil {
+		return nil, r.error(err)
+	}
+	if token != json.Delim('{') {
+		return nil, r.error(fmt.Errorf("expected an object"))
+	}
+	var trailing, leading = jsoncComments(r.source[:r.origin(r.decoder.InputOffset())])
+	if nodes, err = r.object(); err != nil {
+		return
+	}
+	if len(nodes) > 0 {
+		// comments above the object document its first key
+		nodes[0].Doc = joinDoc(joinDoc(trailing, leading), nodes[0].Doc)
+	}
+	if _, err = r.decoder.Token(); err == nil {
+		return nil, r.error(fmt.Errorf("unexpected text after the object"))
+	}
+	return nodes, nil
+}
+
+func (r *jsonReader) error(err error) error {
+	return parseError(r.path, r.source, r.standard, r.origin, err)
+}
+
+// object reads the keys and values of an object after its open brace.
+// Comments on the line of the prior value are added to its doc, the
+// others to the doc of the next key, or of the last key before the
+// closing brace.
+func (r *jsonReader) object() (nodes []node, err error) {
+	for {
+		var from = r.decoder.InputOffset()
+		var token json.Token
+		if token, err = r.decoder.Token(); err != nil {
+			return nil, r.error(err)
+		}
+		var trailing, leading = jsoncComments(r.source[r.origin(from):r.origin(r.decoder.InputOffset())])
+		if len(nodes) > 0 {
+			nodes[len(nodes)-1].Doc = joinDoc(nodes[len(nodes)-1].Doc, trailing)
+		} else {
+			leading = joinDoc(trailing, leading)
+		}
+		if token == json.Delim('}') {
+			if len(nodes) > 0 {
+				nodes[len(nodes)-1].Doc = joinDoc(nodes[len(nodes)-1].Doc, leading)
+			}
+			return
+		}
+		var n = node{Key: token.(string), Doc: leading}
+		var next = r.peek()
+		if next == '{' {
+			if _, err = r.decoder.Token(); err != nil {
+				return nil, r.error(err)
+			}
+			n.Object = true
+			if n.Children, err = r.object(); err != nil {
+				return
+			}
+		} else if err = r.decoder.Decode(&n.Value); err != nil {
+			return nil, r.error(err)
+		}
+		nodes = append(nodes, n)
+	}
+}
+
+// peek at the first byte of the next value, after the colon
+func (r *jsonReader) peek() byte {
+	for i := int(r.decoder.InputOffset()); i < len(r.standard); i++ {
+		switch r.standard[i] {
+		case ' ', '\t', '\r', '\n', ':':
+			continue
+		}
+		return r.standard[i]
+	}
+	return 0
+}
+
+// jsoncComments finds the comments in source text between tokens, the
+// comments before the first newline trail the prior token
+func jsoncComments(text []byte) (trailing, leading string) {
+	var newline bool
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case c == '\n':
+			newline = true
+		case c == '/' && i+1 < len(text) && (text[i+1] == '/' || text[i+1] == '*'):
+			var end = commentEnd(text, i)
+			var body = commentText(string(text[i:end]))
+			if newline {
+				leading = joinDoc(leading, body)
+			} else {
+				trailing = joinDoc(trailing, body)
+			}
+			i = end - 1
+		case c == '"' || c == '\'':
+			// the key, comments end at the token
+			return
+		}
+	}
+	return
+}
+
+// commentText strips the markers of a line or block comment
+func commentText(text string) string {
+	if strings.HasPrefix(text, "//") {
+		return strings.TrimSpace(text[2:])
+	}
+	text = strings.TrimSuffix(strings.TrimPrefix(text, "/*"), "*/")
+	var lines = strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "*"))
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// joinDoc joins doc text with a newline
+func joinDoc(doc, text string) string {
+	switch {
+	case len(doc) == 0:
+		return text
+	case len(text) == 0:
+		return doc
+	}
+	return doc + "\n" + text
+}
+
+// readYAMLNodes decodes a yaml mapping to nodes with its comments
+func readYAMLNodes(path string, text []byte) (nodes []node, err error) {
+	var document yaml.Node
+	if err = yaml.Unmarshal(text, &document); err != nil {
+		return nil, fmt.Errorf("%s %w", path, err)
+	}
+	if len(document.Content) == 0 {
+		return
+	}
+	var root = document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s:%d:%d: expected a mapping", path, root.Line, root.Column)
+	}
+	return yamlNodes(path, root)
+}
+
+func yamlNodes(path string, mapping *yaml.Node) (nodes []node, err error) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		var key, value = mapping.Content[i], mapping.Content[i+1]
+		var n = node{Key: key.Value}
+		for _, text := range []string{key.HeadComment, key.LineComment, value.LineComment, key.FootComment} {
+			n.Doc = joinDoc(n.Doc, yamlComment(text))
+		}
+		if value.Kind == yaml.MappingNode {
+			n.Object = true
+			if n.Children, err = yamlNodes(path, value); err != nil {
+				return
+			}
+			nodes = append(nodes, n)
+			continue
+		}
+		if err = value.Decode(&n.Value); err != nil {
+			return nil, fmt.Errorf("%s:%d:%d: %w", path, value.Line, value.Column, err)
+		}
+		n.Value = jsonValue(n.Value)
+		nodes = append(nodes, n)
+	}
+	return
+}
+
+/*
+yamlJSON converts the yaml text of the file at path to json for
+loading. Each key is written on its line in the yaml so lines in
+errors match the file, origin maps a json offset to the start of the
+yaml line. Syntax errors are returned as a ParseError.
+*/
+func yamlJSON(path string, text []byte) (out []byte, origin func(int64) int64, err error) {
+	var document yaml.Node
+	if err = yaml.Unmarshal(text, &document); err != nil {
+		var pe = &ParseError{Path: path, Err: err}
+		if _, serr := fmt.Sscanf(err.Error(), "yaml: line %d:", &pe.Line); serr == nil {
+			var offset = lineOffset(text, pe.Line)
+			_, pe.Column = position(text, offset)
+			pe.Excerpt = excerpt(text, offset)
+		}
+		return text, nil, pe
+	}
+	if len(document.Content) == 0 {
+		return []byte("{}"), nil, nil
+	}
+	var root = document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return text, nil, fmt.Errorf("%s:%d:%d: expected a mapping", path, root.Line, root.Column)
+	}
+	var buffer bytes.Buffer
+	var line = 1
+	var at = func(n *yaml.Node) {
+		for ; line < n.Line; line++ {
+			buffer.WriteByte('\n')
+		}
+	}
+	var write func(n *yaml.Node) error
+	write = func(n *yaml.Node) error {
+		at(n)
+		if n.Kind != yaml.MappingNode {
+			var value any
+			if err := n.Decode(&value); err != nil {
+				return fmt.Errorf("%s:%d:%d: %w", path, n.Line, n.Column, err)
+			}
+			var data, err = json.Marshal(jsonValue(value))
+			if err != nil {
+				return fmt.Errorf("%s:%d:%d: %w", path, n.Line, n.Column, err)
+			}
+			buffer.Write(data)
+			return nil
+		}
+		buffer.WriteByte('{')
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+			at(n.Content[i])
+			var key, _ = json.Marshal(n.Content[i].Value)
+			buffer.Write(key)
+			buffer.WriteByte(':')
+			if err := write(n.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		buffer.WriteByte('}')
+		return nil
+	}
+	if err = write(root); err != nil {
+		return text, nil, err
+	}
+	out = buffer.Bytes()
+	origin = func(offset int64) int64 {
+		var line, _ = position(out, offset)
+		return lineOffset(text, line)
+	}
+	return
+}
+
+// lineOffset of the first non blank byte of the 1 based line of text
+func lineOffset(text []byte, line int) (offset int64) {
+	for ; line > 1 && offset < int64(len(text)); offset++ {
+		if text[offset] == '\n' {
+			line--
+		}
+	}
+	for offset < int64(len(text)) && (text[offset] == ' ' || text[offset] == '\t') {
+		offset++
+	}
+	return
+}
+
+// yamlComment strips the # markers of yaml comment lines
+func yamlComment(text string) string {
+	var lines = strings.Split(strings.TrimSpace(text), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "#"))
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// jsonValue converts yaml decoded maps with non string keys to json
+// objects
+func jsonValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			v[key] = jsonValue(item)
+		}
+	case map[any]any:
+		var object = make(map[string]any, len(v))
+		for key, item := range v {
+			object[fmt.Sprint(key)] = jsonValue(item)
+		}
+		return object
+	case []any:
+		for i, item := range v {
+			v[i] = jsonValue(item)
+		}
+	}
+	return value
+}
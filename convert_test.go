@@ -0,0 +1,149 @@
+package autocfg
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const convertSource = `{
+  // vault settings
+  vault: {address: 'http://127.0.0.1:8200', ttl: 5,},
+  "role": "reader", // approle
+  "big": 12345678901234567890,
+}
+`
+
+func TestConvert(t *testing.T) {
+	var out, err = Convert([]byte(convertSource), FormatJSONC, FormatYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want = `# vault settings
+vault:
+  address: "http://127.0.0.1:8200"
+  ttl: 5
+# approle
+role: "reader"
+big: 12345678901234567890
+`
+	if string(out) != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, out)
+	}
+	// yaml back to jsonc keeps the order and comments
+	if out, err = Convert(out, FormatYAML, FormatJSONC); err != nil {
+		t.Fatal(err)
+	}
+	for _, text := range []string{"// vault settings\n  \"vault\": {", "// approle\n  \"role\"", `"big": 12345678901234567890`} {
+		if !strings.Contains(string(out), text) {
+			t.Errorf("expected %q in\n%s", text, out)
+		}
+	}
+	if _, err = Convert([]byte("a = 1"), FormatTOML, FormatJSON); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("expected ErrUnsupportedFormat got %v", err)
+	}
+	if _, err = Convert([]byte(convertSource), FormatJSONC, FormatTOML); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("expected ErrUnsupportedFormat writing toml got %v", err)
+	}
+}
+
+type convertConf struct {
+	Vault struct {
+		Address string `json:"address"`
+		TTL     int    `json:"ttl"`
+	} `json:"vault"`
+	Role string `json:"role"`
+}
+
+func TestLoadConverted(t *testing.T) {
+	defer Reset()
+	Reset()
+	var dir = t.TempDir()
+	var src, dst = filepath.Join(dir, "app.jsonc"), filepath.Join(dir, "app.yaml")
+	if err := os.WriteFile(src, []byte(convertSource), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ConvertFile(src, dst, false); err != nil {
+		t.Fatal(err)
+	}
+	var o convertConf
+	if err := LoadDirect(dst, &o); err != nil {
+		t.Fatal(err)
+	}
+	if o.Vault.Address != "http://127.0.0.1:8200" || o.Vault.TTL != 5 || o.Role != "reader" {
+		t.Errorf("expected the converted yaml loaded got %+v", o)
+	}
+	if err := os.WriteFile(dst, []byte("# vault\nvault:\n  ttl: five\nrole: reader\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var pe *ParseError
+	if err := LoadDirect(dst, &convertConf{}); !errors.As(err, &pe) || pe.Line != 3 || pe.Field != "vault.ttl" {
+		t.Errorf("expected a ParseError on line 3 for vault.ttl got %v", err)
+	}
+	if err := os.WriteFile(dst, []byte("vault:\n  ttl: 5\n role: reader\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadDirect(dst, &convertConf{}); !errors.As(err, &pe) || pe.Line == 0 {
+		t.Errorf("expected a located yaml syntax ParseError got %v", err)
+	}
+}
+
+func TestFormatFile(t *testing.T) {
+	var path = filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(convertSource), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var changed, err = FormatFile(path, false)
+	if err != nil || !changed {
+		t.Fatalf("expected an unformatted file got %t %v", changed, err)
+	}
+	if changed, err = FormatFile(path, true); err != nil || !changed {
+		t.Fatalf("expected the file formatted got %t %v", changed, err)
+	}
+	var text, _ = os.ReadFile(path)
+	var want = `{
+  "big": 12345678901234567890,
+  // approle
+  "role": "reader",
+  // vault settings
+  "vault": {
+    "address": "http://127.0.0.1:8200",
+    "ttl": 5
+  }
+}
+`
+	if string(text) != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, text)
+	}
+	if changed, err = FormatFile(path, true); err != nil || changed {
+		t.Errorf("expected a formatted file unchanged got %t %v", changed, err)
+	}
+}
+
+func TestFormatFileHTML(t *testing.T) {
+	var want = "{\n  \"url\": \"https://x/?a=1&b=<2>\"\n}\n"
+	for _, name := range []string{"config.json", "config.yaml"} {
+		var path = filepath.Join(t.TempDir(), name)
+		var text = []byte(want)
+		if name == "config.yaml" {
+			text = []byte("url: \"https://x/?a=1&b=<2>\"\n")
+		}
+		if err := os.WriteFile(path, text, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if changed, err := FormatFile(path, true); err != nil || changed {
+			t.Errorf("%s expected a formatted file unchanged got %t %v", name, changed, err)
+		}
+		var out, _ = os.ReadFile(path)
+		if !bytes.Equal(out, text) {
+			t.Errorf("%s expected\n%s\ngot\n%s", name, text, out)
+		}
+	}
+	var out, err = Convert([]byte(want), FormatJSON, FormatYAML)
+	if err != nil || string(out) != "url: \"https://x/?a=1&b=<2>\"\n" {
+		t.Errorf("expected the url unescaped in yaml got %q %v", out, err)
+	}
+}
@@ -0,0 +1,236 @@
+package autocfg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+// Context of an effective configuration: the environment, profile and
+// env files in place while the configuration is read
+type Context struct {
+	// Name labels the context in a diff
+	Name string `json:"name"`
+	// Env sets env vars in the env overlay while the context is read,
+	// e.g. AUTOCFG_FILENAME or XDG_CONFIG_HOME
+	Env map[string]string `json:"env,omitempty"`
+	// Home replaces ${HOME} and ~ in search paths
+	Home string `json:"home,omitempty"`
+	// Profile replaces Profile selecting .env.{{profile}} files
+	Profile string `json:"profile,omitempty"`
+	// Dotenv loads the DotenvFiles of the context
+	Dotenv bool `json:"dotenv,omitempty"`
+	// EnvFiles are dotenv files loaded into the env overlay
+	EnvFiles []string `json:"env-files,omitempty"`
+}
+
+// enter sets the environment of the context in the env overlay, the
+// returned function restores the prior environment, overlay and
+// profile. HOME alone is set in the process, homedir reads it there.
+func (ctx Context) enter() (restore func(), err error) {
+	var home = ctx.Home
+	if len(home) == 0 {
+		home = ctx.Env["HOME"]
+	}
+	var priorHome, homeSet = os.LookupEnv("HOME")
+	if len(home) > 0 {
+		os.Setenv("HOME", home)
+	}
+	var priorOverlay, priorDotenv, priorProfile = overlay, dotenvSource, Profile
+	restore = func() {
+		switch {
+		case len(home) == 0:
+		case homeSet:
+			os.Setenv("HOME", priorHome)
+		default:
+			os.Unsetenv("HOME")
+		}
+		overlay, dotenvSource, Profile = priorOverlay, priorDotenv, priorProfile
+		homedir.Reset()
+	}
+	homedir.Reset()
+	ResetEnv()
+	// the context env beats env files like the process environment
+	var apply = func() {
+		for key, value := range ctx.Env {
+			if _, fromFile := dotenvSource[key]; key == "HOME" || fromFile && DotenvOverride {
+				continue
+			}
+			overlay[key] = value
+			delete(dotenvSource, key)
+		}
+	}
+	apply()
+	if len(ctx.Profile) > 0 {
+		Profile = ctx.Profile
+	}
+	if ctx.Dotenv {
+		err = LoadDotenvFiles()
+	}
+	for _, path := range ctx.EnvFiles {
+		if err == nil {
+			err = LoadDotenv(ExpandEnvEvalTilde(path))
+		}
+	}
+	apply()
+	return
+}
+
+/*
+EffectiveIn reads the effective configuration in a context. With a
+nil obj it is Effective, the files only. With a pointer to struct obj
+a fresh copy is loaded from the default tags, the files, the env layer
+then the policy file, and sources reports each from Provenance. The
+environment, env overlay, profile and provenance are restored after.
+*/
+func EffectiveIn(ctx Context, obj any) (config map[string]any, sources map[string]Source, err error) {
+	defer Trace.ScopedTrace()()
+	var restore func()
+	restore, err = ctx.enter()
+	defer restore()
+	if err != nil {
+		return
+	}
+	if obj == nil {
+		return Effective()
+	}
+	if !isPtr(obj) {
+		return nil, nil, fmt.Errorf("arg obj any [%T]: object is not a pointer to struct", obj)
+	}
+	var prior = provenance
+	defer func() { provenance = prior }()
+	ResetProvenance()
+	var fresh = reflect.New(reflect.TypeOf(obj).Elem()).Interface()
+	if err = applyDefaults(fresh); err != nil {
+		return
+	}
+	var errs = []error{
+		Load(fresh, DirectFiles(), IndirectFiles()),
+		checkEnv(fresh),
+		enforcePolicy(fresh),
+	}
+	var text []byte
+	if text, err = json.Marshal(fresh); err != nil {
+		return
+	}
+	if err = json.Unmarshal(text, &config); err != nil {
+		return
+	}
+	return config, Provenance(), errors.Join(errs...)
+}
+
+// Side of a Difference, Set is false when the key is absent
+type Side struct {
+	Value  any    `json:"value"`
+	Set    bool   `json:"set"`
+	Source Source `json:"source"`
+}
+
+// Difference of a value between two contexts
+type Difference struct {
+	Key string `json:"key"`
+	A   Side   `json:"a"`
+	B   Side   `json:"b"`
+}
+
+// String formats a side as "value (source)"
+func (side Side) String() string {
+	if !side.Set {
+		return "unset"
+	}
+	var text, _ = json.Marshal(side.Value)
+	if len(side.Source.Layer) == 0 {
+		return string(text)
+	}
+	return fmt.Sprintf("%s (%s)", text, side.Source)
+}
+
+/*
+Diff compares the effective configuration of obj, or of the files
+only when obj is nil, in two contexts. The differences are listed by
+json key path, with the value and source on each side. Errors reading
+either context are returned with the differences found.
+*/
+func Diff(obj any, a, b Context) (diffs []Difference, err error) {
+	defer Trace.ScopedTrace()()
+	var configA, sourcesA, errA = EffectiveIn(a, obj)
+	var configB, sourcesB, errB = EffectiveIn(b, obj)
+	var leavesA, leavesB = leafValues(configA), leafValues(configB)
+	var keys = map[string]bool{}
+	for key := range leavesA {
+		keys[key] = true
+	}
+	for key := range leavesB {
+		keys[key] = true
+	}
+	for _, key := range sortedKeys(keys) {
+		var valueA, setA = leavesA[key]
+		var valueB, setB = leavesB[key]
+		if setA == setB && reflect.DeepEqual(valueA, valueB) {
+			continue
+		}
+		diffs = append(diffs, Difference{
+			Key: key,
+			A:   Side{Value: valueA, Set: setA, Source: sourceOf(sourcesA, key)},
+			B:   Side{Value: valueB, Set: setB, Source: sourceOf(sourcesB, key)},
+		})
+	}
+	if errA != nil {
+		errA = fmt.Errorf("%s: %w", a.Name, errA)
+	}
+	if errB != nil {
+		errB = fmt.Errorf("%s: %w", b.Name, errB)
+	}
+	return diffs, errors.Join(errA, errB)
+}
+
+// leafValues flattens a json tree to its leaf values by dotted key
+// path, arrays and empty objects are leaves
+func leafValues(tree map[string]any) (values map[string]any) {
+	values = map[string]any{}
+	var walk func(object map[string]any, at string)
+	walk = func(object map[string]any, at string) {
+		for key, value := range object {
+			if len(at) > 0 {
+				key = at + "." + key
+			}
+			if child, ok := value.(map[string]any); ok && len(child) > 0 {
+				walk(child, key)
+				continue
+			}
+			values[key] = value
+		}
+	}
+	walk(tree, "")
+	return
+}
+
+// sourceOf the key or the nearest parent key with a source, map field
+// entries share the source of the field
+func sourceOf(sources map[string]Source, key string) Source {
+	for {
+		if source, ok := sources[key]; ok {
+			return source
+		}
+		var i = strings.LastIndex(key, ".")
+		if i < 0 {
+			return Source{}
+		}
+		key = key[:i]
+	}
+}
+
+// sortedKeys of a set
+func sortedKeys(set map[string]bool) (keys []string) {
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return
+}
@@ -0,0 +1,80 @@
+package autocfg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type diffConf struct {
+	Role  string `json:"role" default:"reader"`
+	Vault struct {
+		Addr string `json:"addr"`
+		TTL  int    `json:"ttl"`
+	} `json:"vault"`
+}
+
+func TestDiff(t *testing.T) {
+	var program, saved = Program(), GetMode()
+	defer func() {
+		SetProgram(program)
+		_, _ = SetMode(saved)
+		Reset()
+	}()
+	Reset()
+	t.Setenv("AUTOCFG_FILENAME", "")
+	t.Setenv("ROLE", "")
+	os.Unsetenv("ROLE")
+	SetProgram("diff-test")
+	_, _ = SetMode(Union | Direct)
+	var dir = t.TempDir()
+	var staging, prod = filepath.Join(dir, "staging"), filepath.Join(dir, "prod")
+	var envFile = filepath.Join(dir, "prod.env")
+	for name, text := range map[string]string{
+		filepath.Join(staging, ".config", "diff-test", "config.json"): `{"vault": {"addr": "http://staging", "ttl": 5}}`,
+		filepath.Join(prod, ".config", "diff-test", "config.json"):    `{"vault": {"addr": "http://prod", "ttl": 5}}`,
+		envFile: "ROLE=admin\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(name), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(text), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var home = os.Getenv("HOME")
+	var a = Context{Name: "staging", Home: staging}
+	var b = Context{Name: "prod", Home: prod, EnvFiles: []string{envFile}}
+	var diffs, err = Diff(&diffConf{}, a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 2 {
+		t.Fatalf("expected role and vault.addr to differ got %v", diffs)
+	}
+	var role, addr = diffs[0], diffs[1]
+	if role.Key != "role" || role.A.Value != "reader" || role.A.Source.Layer != DefaultLayer ||
+		role.B.Value != "admin" || role.B.Source.Layer != DotenvLayer {
+		t.Errorf("unexpected role difference %+v", role)
+	}
+	if addr.Key != "vault.addr" || addr.B.Source.Name != filepath.Join(prod, ".config", "diff-test", "config.json") {
+		t.Errorf("unexpected vault.addr difference %+v", addr)
+	}
+	// files only, the env file and defaults do not apply
+	if diffs, err = Diff(nil, a, b); err != nil || len(diffs) != 1 || diffs[0].Key != "vault.addr" {
+		t.Errorf("expected a vault.addr difference got %v %v", diffs, err)
+	}
+	// the context env reaches the env layer through the overlay and
+	// beats env files
+	var c = Context{Name: "ops", Home: prod, Env: map[string]string{"ROLE": "ops"}, EnvFiles: []string{envFile}}
+	var config, sources, cerr = EffectiveIn(c, &diffConf{})
+	if cerr != nil || config["role"] != "ops" || sources["role"].Layer != EnvLayer {
+		t.Errorf("expected role ops from the context env got %v %v %v", config["role"], sources["role"], cerr)
+	}
+	if _, set := os.LookupEnv("ROLE"); set {
+		t.Error("the context env should not be set in the process")
+	}
+	if os.Getenv("HOME") != home || len(Getenv("ROLE")) > 0 {
+		t.Error("Diff should restore the environment")
+	}
+}
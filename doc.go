@@ -37,6 +37,19 @@ auto config is performed the following order:
 The configuration if found can be loaded directly from the path
 returned by FindConfiguration()
 
+The env map of an autocfg file is added to an in memory overlay
+before its configuration file is read, so ${VAR} references in that
+file and the env layer see the values. The process environment is
+only changed when the autocfg file sets "export": true or ExportEnv
+is set.
+
+Configuration string values are interpolated, see Interpolate for
+the ${VAR:-default}, ${VAR:?message} and $$ forms. Keys and other
+JSON structure are never expanded. Interpolation is disabled for a
+field with the tag `interpolate:"false"`, for a file with
+SetInterpolation(path, false) or "interpolate": false in the autocfg
+file pointing to it, and for everything with Interpolation = false.
+
 The order of evaluation of configuration options follows this sequence.
 
 1. file - Files must be created and saved prior to execute. When a
@@ -46,10 +59,231 @@ supplied as the argument to the configuration call.
 2. env - Environment variables are static pre-runtime; but may precede
 the execution call, when an env variable is set, use that value and
 replace any existing value(s) specified in a file configuration loaded
-in 1.
+in 1. Names follow go-cfg unless SetEnvPolicy declares a prefix,
+nested separator and case, e.g. MYAPP_GITHUB__TOKEN_FILE, in which
+case usage lists the policy names.
 
 3. flag - Flags are evaluated from the command line. When flags are
 specified, set corresponding object members from command line flag
-argument and replace option specified in 1. or 2.
+argument and replace option specified in 1. or 2. The command line is
+parsed once; Configure does not call cfg.Freeze, so a program calling
+cfg.Freeze or cfg.Final after it parses the command line again.
+
+When Dotenv is set, KEY=value definitions from DotenvFiles(), .env,
+.{{program-name}}.env and .env.{{profile}} in /etc/{{program-name}},
+~/.config/{{program-name}} and the current directory, are added to
+the env layer before any file is read. Variables already in the
+process environment win unless DotenvOverride is set.
+
+Provenance() reports which default, file, dotenv file, env var or
+flag set each field.
+
+SetPrecedence replaces the fixed order of 1. through 3. with a
+declared list of sources, lowest priority first, for example
+
+	autocfg.SetPrecedence(autocfg.Precedence{
+		"defaults", "xdg", "home", "env", "etc", "flags", "policy",
+	})
+
+lets the environment beat a user's home files while /etc policy
+files still beat the environment. The policy source,
+/etc/{{program-name}}/policy.json, is enforced: it always applies
+last so no other source overrides it. See DefaultPrecedence and
+SourceFiles for the names and their paths. The order set is the
+default of every call; a Loader carries its own order for its calls,
+
+	var loader = autocfg.Loader{Precedence: autocfg.Precedence{
+		"defaults", "etc", "env", "flags",
+	}}
+	err = loader.Configure(&config)
+
+The policy file is enforced with or without a precedence list. Each
+field it sets, e.g. {"insecure-skip-verify": false}, is locked: a
+file, env var or flag setting it to another value is ignored and
+reported as a warning, or an ErrPolicyLocked error when PolicyErrors
+is set. Locked() lists the fields, and Provenance() and String() mark
+them. PolicyPath moves the file for tests and packaging.
+
+A sources tag restricts which sources may set a field,
+
+	Secret string `json:"secret" sources:"file,env"`
+
+keeps the secret off the command line: the flag is hidden from usage
+and a value from an excluded source is discarded with an
+ErrSourceNotAllowed error. The names are default, file, env, dotenv
+and flag; default tags and the policy file always apply.
+
+Renamed keys keep working with an alias tag,
+
+	VaultAddr string `json:"vault-address" alias:"vault-addr"`
+
+reads vault-addr from files, VAULT_ADDR from the environment and
+--vault-addr from the command line, warning that each is deprecated
+with the file and line of a file key. A deprecated tag replaces the
+"use vault-address" advice, and on a field without aliases warns
+whenever the field is set. Warnings() lists them.
+
+Keys in a configuration file that match no field are reported with
+the file, line and the closest field name,
+
+	config.json:2: unknown key "rol", did you mean "role"?
+
+as warnings, or as ErrUnknownKey errors when Strict is set.
+
+Syntax and type errors in a file are returned as a *ParseError with
+the path, line, column, json key path of a mistyped field and the
+source line marked with a caret,
+
+	config.json:3:13: json: cannot unmarshal string into ... (field server.port)
+		    "port": "eighty"
+		            ^
+
+First mode returns the error of the first file found, Union mode
+loads every file found and warns of the errors of each, or joins and
+returns them when Strict is set.
+
+Files named .jsonc or .json5 may use line and block comments, trailing
+commas, unquoted keys and 'single quoted' strings,
+
+	{
+	  // pinned until the 1.15 upgrade, see OPS-112
+	  vault-version: '1.14',
+	}
+
+Setting JSONC accepts the same in .json files. Files named .yaml or
+.yml are read as YAML. Lines and columns in errors and warnings refer
+to the file as written.
+
+GenerateTemplate writes a starting configuration file with every
+field, omitempty fields included, set to its default tag value and
+commented with its doc tag, as JSONC, YAML or TOML by file extension.
+WriteTemplate writes the same to an io.Writer.
+
+Schema returns a JSON Schema, draft 2020-12, of the configuration
+files for editors to complete and check. Doc tags become descriptions,
+default tags defaults, and the required, enum, min, max and pattern
+tags constraints,
+
+	Port int `json:"port" default:"8200" min:"1" max:"65535"`
+
+Setting SchemaRef adds a "$schema" reference to generated templates.
+A struct type nested in itself, through a pointer, slice or map, is
+described once and referred to with "$ref".
+
+Validate checks values against the same tags. Check loads the search
+chain, or named files, and the env layer into a fresh object, treats
+unknown keys as errors and validates, reporting per file
+
+	/etc/ex-app/config.json: ok
+	.ex-app.json:
+	  error: .ex-app.json:2: unknown key "prot", did you mean "port"?
+	environment: ok
+	validation: ok
+	check failed: 1 errors, 0 warnings
+
+Configure adds a --check-config flag running Check and exiting non
+zero on failure, for CI and pre-deploy hooks. Set CheckConfigFlag
+empty to leave it out.
+
+Setting StandardFlags adds flags every application otherwise writes
+for itself,
+
+	--config FILE           load FILE first, like AUTOCFG_FILENAME
+	--config-mode MODE      first or union search mode
+	--print-config          print the redacted configuration and exit
+	--print-search-paths    print the search paths and exit
+	--write-config FILE     write the configuration to FILE and exit
+
+Fields tagged secret:"true", or with json names like password or
+token, print as REDACTED. Redact returns the same json tree.
+
+Configure adds a --completion flag printing a bash, zsh or fish
+completion script for the flags, nested and prefixed names included,
+and exiting. Enum tags complete values, complete:"file" and
+complete:"dir" tags, and fields named *-file, *-path or *-dir,
+complete paths. Completion writes the same script,
+
+	ex-app --completion bash > /etc/bash_completion.d/ex-app
+
+WriteManPage and WriteReference write a roff man page and a Markdown
+reference of the configuration: each field with its flag, env var,
+file key path, type, default and doc tag, the files the search mode
+reads and the AUTOCFG_FILENAME environment variable. Called after
+Configure they use the flag and env names Configure defined.
+
+A Multicall registers the calls of a busybox style program, each with
+a name, description, config struct and run function. Dispatch runs the
+call named by the program, when run by a symlink, else by the first
+argument. The flags are reset for the call so it sees only the flags
+of its config, and help lists the registered calls.
+
+	ex-app approle --role reader
+	approle --role reader
+
+The program files hold the Shared fields of a multicall program at
+the top level and each call in a section of its name, or of its
+Section when set. A call reads
+the shared fields and its own section, the sections of other calls
+are known keys. With CallFiles a call also reads
+${HOME}/.config/{{program}}/{{call}}.json ahead of the program config
+file, holding the shared and call fields at the top level.
+
+	{"vault-address": "https://vault:8200", "approle": {"role": "reader"}}
+
+DispatchTree runs a command of a tree of nested structs, a field with
+a cmd tag is a subcommand and the others are options. A command reads
+the options of every node on its path, so parent options are inherited
+and their flags set at any level, from the file section of each node
+by json name, env vars and flags. Flags are named for the option
+alone, so options on a path sharing a name are an error. A node with
+a Run method is a Runner, a node without one prints its usage
+listing its subcommands.
+
+	tool --vault-address https://vault:8200 vault login approle --role reader
+
+The --install-links DIR argument of a multicall program creates a
+symlink in DIR for each call pointing at the executable, leaving links
+in place, replacing stale links left dangling when the executable
+moved and refusing to replace other files. --verify-links reports
+missing, stale or conflicting links, --remove-links removes them and
+--dry-run reports the changes without making them. InstallLinks is the
+same in code.
+
+	ex-app --install-links ~/bin --dry-run
+
+SearchStatus lists each search path of the program with its status,
+found, missing or parse-error, and the file each autocfg file points
+to. Effective merges the files found as Configure would load them and
+returns the json tree with the file each value came from. SetProgram
+replaces the program name in search paths to inspect another
+application. The cmd/autocfg tool prints both for operators,
+
+	autocfg paths -app ex-app -mode first-direct-indirect
+	autocfg effective -app ex-app -sources
+
+Diff compares the effective configuration in two Contexts, each a
+set of env vars such as AUTOCFG_FILENAME, a home directory, a profile
+and env files, and lists each differing json key path with the value
+and source on either side,
+
+	autocfg diff -app ex-app -a.name staging -a.env-file staging.env \
+	  -b.name prod -b.home /srv/prod
+	vault.addr
+	  staging: "http://staging:8200" (file /etc/ex-app/config.json)
+	  prod: "http://prod:8200" (file /srv/prod/.config/ex-app/config.json)
+
+Convert reads and writes JSON, JSONC or YAML, keeping key order and
+the comments above keys where the format has comments. ConvertFile
+picks formats by file extension. Canonical and FormatFile sort keys
+and indent by two spaces for clean diffs,
+
+	autocfg convert config.json config.yaml
+	autocfg fmt -l config.jsonc
+	autocfg fmt -check /etc/ex-app/*.json
+
+4. references - String values may reference other fields by json key
+path, "${.data-dir}/logs" or "auth/${approle.mount}/login". After
+1. through 3. ResolveReferences substitutes the final value of the
+referenced field.
 */
 package autocfg
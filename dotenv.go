@@ -0,0 +1,192 @@
+package autocfg
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Dotenv enables loading dotenv files into the env layer
+var Dotenv bool
+
+// DotenvOverride lets dotenv definitions replace variables already set
+// in the process environment
+var DotenvOverride bool
+
+// Profile selects .env.{{profile}} files, AUTOCFG_PROFILE when unset
+var Profile string
+
+// dotenvSource maps variables defined by dotenv files to the file
+var dotenvSource = map[string]string{}
+
+// profile in effect
+func profile() string {
+	if len(Profile) > 0 {
+		return Profile
+	}
+	return Getenv("AUTOCFG_PROFILE")
+}
+
+// DotenvFiles returns the dotenv search paths in load order, later
+// files replace definitions from earlier ones
+//
+//   - /etc/{{pgm}}/
+//   - ~/.config/{{pgm}}/
+//   - the current directory
+//
+// and in each directory .env, .{{pgm}}.env then .env.{{profile}}
+func DotenvFiles() (paths []string) {
+	defer Trace.ScopedTrace()()
+	var dirs = []string{
+		fmt.Sprintf("/etc/%s", pgm),
+		fmt.Sprintf("${HOME}/.config/%s", pgm),
+		".",
+	}
+	var names = []string{".env", fmt.Sprintf(".%s.env", pgm)}
+	if p := profile(); len(p) > 0 {
+		names = append(names, ".env."+p)
+	}
+	for _, dir := range dirs {
+		for _, name := range names {
+			paths = append(paths, filepath.Join(dir, name))
+		}
+	}
+	return
+}
+
+// LoadDotenvFiles loads each dotenv file found in DotenvFiles
+func LoadDotenvFiles() (err error) {
+	defer Trace.ScopedTrace()()
+	for _, path := range DotenvFiles() {
+		if err = LoadDotenv(ExpandEnvEvalTilde(path)); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// LoadDotenv adds the definitions in a dotenv file to the env overlay.
+// Variables set in the process environment are kept unless
+// DotenvOverride is set. A missing file is not an error.
+func LoadDotenv(path string) (err error) {
+	defer Trace.ScopedTrace()()
+	var text []byte
+	if text, err = os.ReadFile(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			err = nil
+		}
+		return
+	}
+	var pairs [][2]string
+	if pairs, err = parseDotenv(string(text)); err != nil {
+		return fmt.Errorf("%s:%w", path, err)
+	}
+	for _, pair := range pairs {
+		if _, set := os.LookupEnv(pair[0]); set && !DotenvOverride {
+			continue
+		}
+		overlay[pair[0]] = pair[1]
+		dotenvSource[pair[0]] = path
+	}
+	return
+}
+
+/*
+parseDotenv reads KEY=value lines compatible with common dotenv tools
+
+  - blank lines and lines starting with # are ignored
+  - an optional leading "export " is dropped
+  - unquoted values are trimmed and end at " #"
+  - 'single quoted' values are literal and may span lines
+  - "double quoted" values may span lines, support \n \r \t \" \\ \$
+    escapes and ${VAR} expansion
+  - unquoted values support ${VAR} expansion
+
+Expansion sees earlier definitions in the same file.
+*/
+func parseDotenv(text string) (pairs [][2]string, err error) {
+	var defined = map[string]string{}
+	var lookup = func(name string) (string, bool) {
+		if value, ok := defined[name]; ok {
+			return value, true
+		}
+		return LookupEnv(name)
+	}
+	var line = 1
+	for len(text) > 0 {
+		var current string
+		current, text, _ = strings.Cut(text, "\n")
+		var start = line
+		line++
+		current = strings.TrimSpace(current)
+		if len(current) == 0 || current[0] == '#' {
+			continue
+		}
+		current = strings.TrimPrefix(current, "export ")
+		var key, value, found = strings.Cut(current, "=")
+		key = strings.TrimSpace(key)
+		if !found || len(key) == 0 {
+			return nil, fmt.Errorf("%d: expected KEY=value", start)
+		}
+		value = strings.TrimLeft(value, " \t")
+		if len(value) > 0 && (value[0] == '\'' || value[0] == '"') {
+			var quote = value[0]
+			value = value[1:]
+			// a quoted value continues until its closing quote
+			for closing(value, quote) < 0 {
+				if len(text) == 0 {
+					return nil, fmt.Errorf("%d: unterminated %c quote", start, quote)
+				}
+				var next string
+				next, text, _ = strings.Cut(text, "\n")
+				line++
+				value += "\n" + next
+			}
+			value = value[:closing(value, quote)]
+			if quote == '"' {
+				value = unescape(value)
+				value = expandWith(value, lookup)
+			}
+		} else {
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = value[:i]
+			}
+			value = expandWith(strings.TrimSpace(value), lookup)
+		}
+		defined[key] = value
+		pairs = append(pairs, [2]string{key, value})
+	}
+	return
+}
+
+// closing index of the unescaped quote ending value
+func closing(value string, quote byte) int {
+	for i := 0; i < len(value); i++ {
+		switch {
+		case value[i] == '\\' && quote == '"':
+			i++
+		case value[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+var unescaper = strings.NewReplacer(`\n`, "\n", `\r`, "\r", `\t`, "\t", `\"`, `"`, `\\`, `\`, `\$`, "$$")
+
+func unescape(value string) string {
+	return unescaper.Replace(value)
+}
+
+// expandWith interpolates ${VAR} forms using lookup, leaving text
+// that fails to interpolate unchanged
+func expandWith(value string, lookup func(string) (string, bool)) string {
+	var text, err = interpolate(value, lookup)
+	if err != nil {
+		return value
+	}
+	return text
+}
@@ -0,0 +1,63 @@
+package autocfg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseDotenv(t *testing.T) {
+	defer ResetEnv()
+	var text = `# comment
+export USER_NAME=alice
+GREETING="hello ${USER_NAME}\tthere"
+LITERAL='${USER_NAME} $$'
+INLINE=value # trailing comment
+MULTI="line one
+line two"
+EMPTY=
+`
+	var pairs, err = parseDotenv(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want = [][2]string{
+		{"USER_NAME", "alice"},
+		{"GREETING", "hello alice\tthere"},
+		{"LITERAL", "${USER_NAME} $$"},
+		{"INLINE", "value"},
+		{"MULTI", "line one\nline two"},
+		{"EMPTY", ""},
+	}
+	if len(pairs) != len(want) {
+		t.Fatalf("got %q want %q", pairs, want)
+	}
+	for i := range want {
+		if pairs[i] != want[i] {
+			t.Errorf("pair %d got %q want %q", i, pairs[i], want[i])
+		}
+	}
+	if _, err = parseDotenv("OPEN=\"never closed\n"); err == nil {
+		t.Error("expected unterminated quote error")
+	}
+}
+
+func TestLoadDotenvKeepsEnvironment(t *testing.T) {
+	defer ResetEnv()
+	var path = filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte("AUTOCFG_TEST_DOTENV=dotenv\nHOME=/nowhere\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadDotenv(path); err != nil {
+		t.Fatal(err)
+	}
+	if Getenv("AUTOCFG_TEST_DOTENV") != "dotenv" {
+		t.Error("dotenv variable not loaded")
+	}
+	if Getenv("HOME") == "/nowhere" {
+		t.Error("dotenv replaced a process environment variable")
+	}
+	if source := envSource("AUTOCFG_TEST_DOTENV"); source.Layer != DotenvLayer {
+		t.Errorf("expected dotenv source got %s", source)
+	}
+}
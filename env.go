@@ -0,0 +1,104 @@
+package autocfg
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/davidwalter0/go-cfg"
+)
+
+// ExportEnv when true copies each AutoCfg.Env entry into the process
+// environment with os.Setenv in addition to the in memory overlay.
+var ExportEnv bool
+
+// overlay holds env vars from AutoCfg.Env maps, scoped to autocfg
+// evaluation rather than the process environment
+var overlay = map[string]string{}
+
+func init() {
+	// the env layer of go-cfg reads through the overlay
+	cfg.LookupEnv = cfgLookupEnv
+}
+
+// LookupEnv returns the overlay value for key when set, otherwise the
+// process environment value
+func LookupEnv(key string) (value string, ok bool) {
+	defer Trace.ScopedTrace()()
+	if value, ok = overlay[key]; ok {
+		return
+	}
+	return os.LookupEnv(key)
+}
+
+// Getenv like os.Getenv with the overlay applied
+func Getenv(key string) string {
+	defer Trace.ScopedTrace()()
+	var value, _ = LookupEnv(key)
+	return value
+}
+
+// Setenv adds key=value to the overlay, and to the process
+// environment when export is true
+func Setenv(key, value string, export bool) (err error) {
+	defer Trace.ScopedTrace()()
+	overlay[key] = value
+	if export || ExportEnv {
+		err = os.Setenv(key, value)
+	}
+	return
+}
+
+// Environ returns the process environment with the overlay applied
+// in os.Environ's key=value form
+func Environ() (env []string) {
+	defer Trace.ScopedTrace()()
+	var merged = map[string]string{}
+	for _, e := range os.Environ() {
+		if key, value, found := strings.Cut(e, "="); found {
+			merged[key] = value
+		}
+	}
+	for key, value := range overlay {
+		merged[key] = value
+	}
+	for key, value := range merged {
+		env = append(env, key+"="+value)
+	}
+	sort.Strings(env)
+	return
+}
+
+// ResetEnv clears the overlay
+func ResetEnv() {
+	defer Trace.ScopedTrace()()
+	overlay = map[string]string{}
+	dotenvSource = map[string]string{}
+}
+
+// ExpandEnv like os.ExpandEnv with the overlay applied
+func ExpandEnv(text string) string {
+	defer Trace.ScopedTrace()()
+	return os.Expand(text, Getenv)
+}
+
+// applyEnv adds an AutoCfg.Env map to the overlay. Values may
+// reference the environment or previously applied entries.
+func applyEnv(env map[string]string, export bool) (err error) {
+	defer Trace.ScopedTrace()()
+	var keys = make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		var value string
+		if value, err = Interpolate(env[k]); err != nil {
+			return
+		}
+		if err = Setenv(k, value, export); err != nil {
+			return
+		}
+	}
+	return
+}
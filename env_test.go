@@ -0,0 +1,33 @@
+package autocfg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadIndirectEnvOverlay(t *testing.T) {
+	defer ResetEnv()
+	var dir = t.TempDir()
+	var config = filepath.Join(dir, "config.json")
+	var auto = filepath.Join(dir, "autocfg.json")
+	if err := os.WriteFile(config, []byte(`{"vault-address": "${AUTOCFG_TEST_ADDR}"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(auto, []byte(`{"path": "`+config+`", "env": {"AUTOCFG_TEST_ADDR": "https://vault.test"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var o = &fakeTestConf{}
+	if err := LoadIndirect(auto, o); err != nil {
+		t.Fatal(err)
+	}
+	if o.VaultAddr != "https://vault.test" {
+		t.Errorf("expected env overlay expansion, got %q", o.VaultAddr)
+	}
+	if _, ok := os.LookupEnv("AUTOCFG_TEST_ADDR"); ok {
+		t.Error("overlay leaked into the process environment")
+	}
+	if v, ok := LookupEnv("AUTOCFG_TEST_ADDR"); !ok || v != "https://vault.test" {
+		t.Errorf("LookupEnv overlay got %q %t", v, ok)
+	}
+}
@@ -0,0 +1,213 @@
+package autocfg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+
+	eflag "github.com/davidwalter0/go-flag"
+)
+
+// EnvCase selects the letter case of env layer variable names
+type EnvCase int
+
+const (
+	// UpperCase names, MYAPP_GITHUB__TOKEN_FILE
+	UpperCase EnvCase = iota
+	// LowerCase names, myapp_github__token_file
+	LowerCase
+	// KeepCase uses json names as written, hyphens become underscores
+	KeepCase
+)
+
+/*
+EnvPolicy names the environment variables of the env layer. The
+json key path of a field is joined with Separator after each part is
+cased and hyphens are replaced with underscores, then Prefix is
+prepended with an underscore. With Prefix "MYAPP" and the default
+Separator "__" the field at github.token-file is read from
+MYAPP_GITHUB__TOKEN_FILE.
+
+When a policy is set only the variables it names apply, the go-cfg
+names derived from struct names and CFG_KEY_PREFIX are ignored.
+*/
+type EnvPolicy struct {
+	Prefix    string
+	Separator string
+	Case      EnvCase
+}
+
+// envPolicy when set replaces go-cfg env naming
+var envPolicy *EnvPolicy
+
+// SetEnvPolicy for the env layer of Configure and the multicall
+// variants
+func SetEnvPolicy(policy EnvPolicy) {
+	defer Trace.ScopedTrace()()
+	if len(policy.Separator) == 0 {
+		policy.Separator = "__"
+	}
+	envPolicy = &policy
+}
+
+// ClearEnvPolicy restores go-cfg env naming
+func ClearEnvPolicy() {
+	defer Trace.ScopedTrace()()
+	envPolicy = nil
+}
+
+// Name of the env var for the json key path
+func (policy EnvPolicy) Name(path []string) string {
+	var parts = make([]string, len(path))
+	for i, key := range path {
+		parts[i] = policy.cased(strings.ReplaceAll(key, "-", "_"))
+	}
+	var name = strings.Join(parts, policy.Separator)
+	if len(policy.Prefix) > 0 {
+		name = policy.cased(policy.Prefix) + "_" + name
+	}
+	return name
+}
+
+func (policy EnvPolicy) cased(text string) string {
+	switch policy.Case {
+	case LowerCase:
+		return strings.ToLower(text)
+	case KeepCase:
+		return text
+	}
+	return strings.ToUpper(text)
+}
+
+// EnvNames maps the json key path of each field of obj to the env var
+// the current policy reads it from, empty without a policy
+func EnvNames(obj any) (names map[string]string) {
+	defer Trace.ScopedTrace()()
+	names = map[string]string{}
+	if envPolicy == nil {
+		return
+	}
+	for _, l := range leaves(obj) {
+		names[l.Key()] = envPolicy.Name(l.Path)
+	}
+	return
+}
+
+// cfgLookupEnv is the env lookup of go-cfg, disabled for field names
+// when a policy is set so only the policy names apply
+func cfgLookupEnv(key string) (string, bool) {
+	if envPolicy != nil && key != "CFG_KEY_PREFIX" && key != "CFG_DECORATE" {
+		return "", false
+	}
+	return LookupEnv(key)
+}
+
+// envHelpText lists policy env var names for usage output
+var envHelpText string
+
+// envLayer sets the fields of obj from the env vars named by the
+// policy, and relabels the flag usage text with those names
+func envLayer(obj any) (err error) {
+	defer Trace.ScopedTrace()()
+	if envPolicy == nil {
+		return
+	}
+	var flags = flagsByAddr()
+	var errs []error
+	var help = []string{"Environment variables:", ""}
+	for _, l := range leaves(obj) {
+		var name = envPolicy.Name(l.Path)
+		if !allowed(l.Field, EnvLayer) {
+			if _, ok := LookupEnv(name); ok {
+				errs = append(errs, notAllowed(l, envSource(name)))
+			}
+			continue
+		}
+		help = append(help, fmt.Sprintf("  %-40s %s", name, l.Key()))
+		if flag, ok := flags[l.Addr()]; ok {
+			flag.Usage = relabel(flag.Usage, name)
+		}
+		if text, ok := LookupEnv(name); ok {
+			if perr := setValue(l.Value, text); perr != nil {
+				errs = append(errs, fmt.Errorf("env %s: %w", name, perr))
+				continue
+			}
+			setSource(l.Key(), envSource(name))
+		}
+	}
+	envHelpText = strings.Join(help, "\n")
+	setHelpText(envHelpText)
+	return errors.Join(errs...)
+}
+
+var envLabel = regexp.MustCompile(`Env \S+\s*:`)
+
+// relabel the go-cfg "Env NAME :" usage text with name
+func relabel(usage, name string) string {
+	return envLabel.ReplaceAllLiteralString(usage, fmt.Sprintf("Env %-32s :", name))
+}
+
+// flagsByAddr maps the address of each field with a flag to the flag,
+// go-flag values are the field pointer converted to a flag value type,
+// alias flags are skipped
+func flagsByAddr() (flags map[uintptr]*eflag.Flag) {
+	flags = map[uintptr]*eflag.Flag{}
+	eflag.VisitAll(func(flag *eflag.Flag) {
+		if _, alias := aliasFlags[flag.Name]; alias {
+			return
+		}
+		if v := reflect.ValueOf(flag.Value); v.Kind() == reflect.Ptr {
+			flags[v.Pointer()] = flag
+		}
+	})
+	return
+}
+
+// flagErr is the error parsing the command line, eflag.ErrHelp when
+// -h or -help printed the usage
+var flagErr error
+
+// parseFlags parses the command line once as cfg.Freeze does, keeping
+// the error the parser reports. The go-cfg frozen state is left
+// unset, cfg.Freeze after it would print a flag error twice.
+func parseFlags() {
+	if !eflag.Parsed() {
+		flagErr = eflag.CommandLine.Parse(os.Args[1:])
+	}
+}
+
+// defineFlags registers the flags of obj with define, applies the env
+// layer, parses the command line then enforces the policy file,
+// recording the source of each value and rejecting sources a field's
+// sources tag excludes
+func defineFlags(obj any, define func(any) error) (err error) {
+	defer Trace.ScopedTrace()()
+	var prior = restricted(obj, EnvLayer)
+	if err = define(obj); err != nil {
+		return
+	}
+	defineAliases(obj)
+	defineCheckFlag()
+	defineStandardFlags()
+	defineCompletionFlag()
+	hideRestricted(obj)
+	recordDefined(obj)
+	var errs = []error{revertLayer(obj, prior, EnvLayer, DotenvLayer)}
+	if err = envLayer(obj); err != nil {
+		return
+	}
+	if err = aliasEnv(obj); err != nil {
+		return
+	}
+	prior = restricted(obj, FlagLayer)
+	parseFlags()
+	runCheckFlag(obj)
+	runCompletionFlag(obj)
+	recordFlags(obj)
+	warnDeprecated(obj)
+	errs = append(errs, revertLayer(obj, prior, FlagLayer), enforcePolicy(obj))
+	return errors.Join(errs...)
+}
@@ -0,0 +1,34 @@
+package autocfg
+
+import (
+	"testing"
+)
+
+func TestEnvPolicy(t *testing.T) {
+	defer ClearEnvPolicy()
+	defer ResetEnv()
+	type github struct {
+		TokenFile string `json:"token-file"`
+	}
+	type conf struct {
+		Debug  bool   `json:"debug"`
+		Github github `json:"github"`
+	}
+	SetEnvPolicy(EnvPolicy{Prefix: "myapp"})
+	var names = EnvNames(&conf{})
+	if names["github.token-file"] != "MYAPP_GITHUB__TOKEN_FILE" || names["debug"] != "MYAPP_DEBUG" {
+		t.Errorf("unexpected names %v", names)
+	}
+	Setenv("MYAPP_GITHUB__TOKEN_FILE", "/secrets/token", false)
+	Setenv("MYAPP_DEBUG", "true", false)
+	var o = &conf{}
+	if err := envLayer(o); err != nil {
+		t.Fatal(err)
+	}
+	if !o.Debug || o.Github.TokenFile != "/secrets/token" {
+		t.Errorf("env layer not applied %+v", o)
+	}
+	if _, ok := cfgLookupEnv("MYAPP_DEBUG"); ok {
+		t.Error("go-cfg env lookup should be disabled under a policy")
+	}
+}
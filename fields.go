@@ -0,0 +1,307 @@
+package autocfg
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	eflag "github.com/davidwalter0/go-flag"
+)
+
+// jsonName of a struct field from its json tag, the Go field name
+// when the tag has no name, and "-" when the field is skipped
+func jsonName(sf reflect.StructField) (name string) {
+	name, _, _ = strings.Cut(sf.Tag.Get("json"), ",")
+	if len(name) == 0 {
+		name = sf.Name
+	}
+	return
+}
+
+// isPromoted reports an untagged embedded struct whose fields
+// encoding/json promotes to the parent object
+func isPromoted(sf reflect.StructField) bool {
+	var t = sf.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	return sf.Anonymous && len(name) == 0 && t.Kind() == reflect.Struct
+}
+
+// structFields lists the json visible fields of t, descending into
+// promoted embedded structs, with the index path to each field
+func structFields(t reflect.Type) (fields []reflect.StructField) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < t.NumField(); i++ {
+		var sf = t.Field(i)
+		if isPromoted(sf) {
+			for _, inner := range structFields(sf.Type) {
+				inner.Index = append([]int{i}, inner.Index...)
+				fields = append(fields, inner)
+			}
+			continue
+		}
+		if !sf.IsExported() || jsonName(sf) == "-" {
+			continue
+		}
+		fields = append(fields, sf)
+	}
+	return
+}
+
+// lookupField finds the field of t that encoding/json would decode
+// key into, preferring an exact match then a case insensitive one
+func lookupField(t reflect.Type, key string) (field reflect.StructField, ok bool) {
+	var fields = structFields(t)
+	for _, sf := range fields {
+		if jsonName(sf) == key {
+			return sf, true
+		}
+	}
+	for _, sf := range fields {
+		if strings.EqualFold(jsonName(sf), key) {
+			return sf, true
+		}
+	}
+	return
+}
+
+// elemType strips pointers from t
+func elemType(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// leaf is a configurable field of a configuration object, addressed
+// by its json key path
+type leaf struct {
+	Path  []string
+	Field reflect.StructField
+	Value reflect.Value
+}
+
+// Key of the leaf, its json key path joined with dots
+func (l leaf) Key() string {
+	return strings.Join(l.Path, ".")
+}
+
+// Addr of the field value, matching the address go-flag values wrap
+func (l leaf) Addr() uintptr {
+	return l.Value.Addr().Pointer()
+}
+
+// leaves of obj, the non struct fields reachable through nested and
+// embedded structs in declaration order
+func leaves(obj any) (list []leaf) {
+	var v = reflect.ValueOf(obj)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	return appendLeaves(list, v, nil)
+}
+
+func appendLeaves(list []leaf, v reflect.Value, at []string) []leaf {
+	for _, sf := range structFields(v.Type()) {
+		var fv, err = v.FieldByIndexErr(sf.Index)
+		if err != nil {
+			continue
+		}
+		var path = append(append([]string{}, at...), jsonName(sf))
+		if fv.Kind() == reflect.Struct && !isScalarStruct(fv.Type()) {
+			list = appendLeaves(list, fv, path)
+			continue
+		}
+		list = append(list, leaf{Path: path, Field: sf, Value: fv})
+	}
+	return list
+}
+
+// deepCopy of v sharing no map, slice or pointer storage with it, so
+// a later unmarshal into v leaves the copy as it was
+func deepCopy(v reflect.Value) reflect.Value {
+	var copied = reflect.New(v.Type()).Elem()
+	switch v.Kind() {
+	case reflect.Map:
+		if v.IsNil() {
+			return copied
+		}
+		copied.Set(reflect.MakeMapWithSize(v.Type(), v.Len()))
+		for iter := v.MapRange(); iter.Next(); {
+			copied.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+		}
+	case reflect.Slice:
+		if v.IsNil() {
+			return copied
+		}
+		copied.Set(reflect.MakeSlice(v.Type(), v.Len(), v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			copied.Index(i).Set(deepCopy(v.Index(i)))
+		}
+	case reflect.Ptr:
+		if v.IsNil() {
+			return copied
+		}
+		copied.Set(reflect.New(v.Type().Elem()))
+		copied.Elem().Set(deepCopy(v.Elem()))
+	case reflect.Interface:
+		if !v.IsNil() {
+			copied.Set(deepCopy(v.Elem()))
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			copied.Index(i).Set(deepCopy(v.Index(i)))
+		}
+	case reflect.Struct:
+		// unexported fields are copied as they are
+		copied.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if copied.Field(i).CanSet() {
+				copied.Field(i).Set(deepCopy(v.Field(i)))
+			}
+		}
+	default:
+		copied.Set(v)
+	}
+	return copied
+}
+
+// flagLeaves maps the name of each flag defined for a field of obj to
+// the field leaf, by address when the flags were defined for obj, else
+// by the longest field name ending the flag name, so nested and
+// prefixed names match. Alias flags are skipped.
+func flagLeaves(obj any) (fields map[string]leaf) {
+	fields = map[string]leaf{}
+	var list = leaves(obj)
+	var byAddr = map[uintptr]leaf{}
+	var byName = map[string]leaf{}
+	for _, l := range list {
+		byAddr[l.Addr()] = l
+		byName[cfgFlagName(l.Path)] = l
+		if name := cfgFlagName(l.Path[len(l.Path)-1:]); len(byName[name].Path) == 0 {
+			byName[name] = l
+		}
+	}
+	eflag.VisitAll(func(flag *eflag.Flag) {
+		if _, alias := aliasFlags[flag.Name]; alias {
+			return
+		}
+		if v := reflect.ValueOf(flag.Value); v.Kind() == reflect.Ptr {
+			if l, ok := byAddr[v.Pointer()]; ok {
+				fields[flag.Name] = l
+				return
+			}
+		}
+		var longest int
+		for name, l := range byName {
+			if len(name) > longest && (flag.Name == name || strings.HasSuffix(flag.Name, "-"+name)) {
+				fields[flag.Name], longest = l, len(name)
+			}
+		}
+	})
+	return
+}
+
+// isScalarStruct reports struct types set from text rather than by
+// field, like time.Time
+func isScalarStruct(t reflect.Type) bool {
+	return reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setValue parses text into v using the same text forms as the flag
+// and env layers: comma separated slices and key:value,... maps
+func setValue(v reflect.Value, text string) (err error) {
+	if v.CanAddr() && reflect.PointerTo(v.Type()).Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text))
+	}
+	text = strings.TrimSpace(text)
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setValue(v.Elem(), text)
+	case reflect.String:
+		v.SetString(text)
+	case reflect.Bool:
+		var b bool
+		if b, err = strconv.ParseBool(text); err == nil {
+			v.SetBool(b)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Type() == durationType {
+			var d time.Duration
+			if d, err = time.ParseDuration(text); err == nil {
+				v.SetInt(int64(d))
+			}
+			return
+		}
+		var i int64
+		if i, err = strconv.ParseInt(text, 0, v.Type().Bits()); err == nil {
+			v.SetInt(i)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var u uint64
+		if u, err = strconv.ParseUint(text, 0, v.Type().Bits()); err == nil {
+			v.SetUint(u)
+		}
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		if f, err = strconv.ParseFloat(text, v.Type().Bits()); err == nil {
+			v.SetFloat(f)
+		}
+	case reflect.Slice:
+		var parts []string
+		if len(text) > 0 {
+			parts = strings.Split(text, ",")
+		}
+		var slice = reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err = setValue(slice.Index(i), part); err != nil {
+				return
+			}
+		}
+		v.Set(slice)
+	case reflect.Map:
+		var m = reflect.MakeMap(v.Type())
+		for _, pair := range strings.Split(text, ",") {
+			if len(strings.TrimSpace(pair)) == 0 {
+				continue
+			}
+			var key, value, found = strings.Cut(pair, ":")
+			if !found {
+				return fmt.Errorf("invalid map entry %q, expected key:value", pair)
+			}
+			var kv = reflect.New(v.Type().Key()).Elem()
+			var vv = reflect.New(v.Type().Elem()).Elem()
+			if err = setValue(kv, key); err != nil {
+				return
+			}
+			if err = setValue(vv, value); err != nil {
+				return
+			}
+			m.SetMapIndex(kv, vv)
+		}
+		v.Set(m)
+	default:
+		err = fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return
+}
@@ -4,14 +4,15 @@ go 1.21.0
 
 require (
 	github.com/davidwalter0/go-cfg v1.5.0
+	github.com/davidwalter0/go-flag v0.3.0-rc.0
 	github.com/davidwalter0/go-tracer v0.0.1
 	github.com/hashicorp/vault/api v1.10.0
 	github.com/mitchellh/go-homedir v1.1.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/cenkalti/backoff/v3 v3.0.0 // indirect
-	github.com/davidwalter0/go-flag v0.3.0-rc.0 // indirect
 	github.com/fatih/color v1.10.0 // indirect
 	github.com/go-jose/go-jose/v3 v3.0.0 // indirect
 	github.com/hashicorp/errwrap v1.1.0 // indirect
@@ -35,5 +36,4 @@ require (
 	golang.org/x/sys v0.5.0 // indirect
 	golang.org/x/text v0.7.0 // indirect
 	golang.org/x/time v0.0.0-20200416051211-89c76fbcd5d1 // indirect
-	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
 )
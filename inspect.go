@@ -0,0 +1,229 @@
+package autocfg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+// search path status values
+const (
+	StatusFound      = "found"
+	StatusMissing    = "missing"
+	StatusParseError = "parse-error"
+)
+
+// PathStatus of a configuration search path
+type PathStatus struct {
+	Path string `json:"path"`
+	// Indirect files are autocfg files pointing to Target
+	Indirect bool   `json:"indirect,omitempty"`
+	Status   string `json:"status"`
+	Target   string `json:"target,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// String formats the status as "status path -> target: error"
+func (status PathStatus) String() (text string) {
+	text = fmt.Sprintf("%-11s %s", status.Status, status.Path)
+	if len(status.Target) > 0 {
+		text += " -> " + status.Target
+	}
+	if len(status.Error) > 0 {
+		text += ": " + status.Error
+	}
+	return
+}
+
+// SearchStatus reports each configuration search path of the current
+// program and mode in search order, the policy file last
+func SearchStatus() (list []PathStatus) {
+	defer Trace.ScopedTrace()()
+	var add = func(path string) {
+		var status = PathStatus{Path: ExpandEnvEvalTilde(path), Status: StatusFound}
+		if !exists(status.Path) {
+			list = append(list, PathStatus{Path: status.Path, Status: StatusMissing})
+			return
+		}
+		var _, target, err = readTree(status.Path)
+		status.Indirect, status.Target = len(target) > 0, target
+		switch {
+		case err != nil && status.Indirect && !exists(target):
+			status.Error = "target " + StatusMissing
+		case err != nil:
+			status.Status = StatusParseError
+			status.Error = err.Error()
+		}
+		list = append(list, status)
+	}
+	if mode&Direct == Direct || precedence != nil {
+		for _, path := range DirectFiles() {
+			add(path)
+		}
+	}
+	if mode&Indirect == Indirect || precedence != nil {
+		for _, path := range IndirectFiles() {
+			add(path)
+		}
+	}
+	if precedence == nil {
+		add(PolicyFile())
+	}
+	return
+}
+
+// readTree decodes the configuration file at path to a json tree.
+// An autocfg file applies its env and reads the file it points to,
+// returned as target.
+func readTree(path string) (tree map[string]any, target string, err error) {
+	var text []byte
+	if text, err = os.ReadFile(path); err != nil {
+		return
+	}
+	var standard []byte
+	var origin func(int64) int64
+	if standard, origin, err = standardJSON(path, text); err != nil {
+		return
+	}
+	var autoCfg = &AutoCfg{}
+	if json.Unmarshal(standard, autoCfg) == nil && len(autoCfg.Path) > 0 {
+		if target, err = homedir.Expand(ExpandEnv(autoCfg.Path)); err != nil {
+			return
+		}
+		if err = applyEnv(autoCfg.Env, autoCfg.Export); err != nil {
+			return
+		}
+		if text, err = os.ReadFile(target); err != nil {
+			return
+		}
+		path = target
+		if standard, origin, err = standardJSON(path, text); err != nil {
+			return
+		}
+		if autoCfg.Interpolate != nil && !*autoCfg.Interpolate {
+			err = decodeObject(path, text, standard, standard, origin, &tree)
+			return
+		}
+	}
+	var expanded = standard
+	if interpolates(path) {
+		if expanded, err = interpolateJSON(standard, nil); err != nil {
+			return nil, target, fmt.Errorf("%s %w", path, err)
+		}
+	}
+	if err = decodeObject(path, text, standard, expanded, origin, &tree); err == nil && isCallFile(path) {
+		tree = view.section(tree)
+	}
+	return
+}
+
+// decodeObject decodes json text to an object tree, locating errors
+// in the source text
+func decodeObject(path string, source, standard, text []byte, origin func(int64) int64, tree *map[string]any) error {
+	var decoded any
+	if err := json.Unmarshal(text, &decoded); err != nil {
+		return parseError(path, source, standard, origin, err)
+	}
+	var ok bool
+	if *tree, ok = decoded.(map[string]any); !ok {
+		return parseError(path, source, standard, origin, fmt.Errorf("expected an object"))
+	}
+	return nil
+}
+
+// mergeTree merges src into dst like encoding/json decoding into a
+// struct, objects merge key by key and other values replace, setting
+// the source of each leaf
+func mergeTree(dst, src map[string]any, at []string, sources map[string]Source, source Source) {
+	for key, value := range src {
+		var path = append(append([]string{}, at...), key)
+		if child, ok := value.(map[string]any); ok {
+			var existing, isObject = dst[key].(map[string]any)
+			if !isObject {
+				existing = map[string]any{}
+				dst[key] = existing
+			}
+			mergeTree(existing, child, path, sources, source)
+			continue
+		}
+		dst[key] = value
+		var prefix = strings.Join(path, ".")
+		for k := range sources {
+			if strings.HasPrefix(k, prefix+".") {
+				delete(sources, k)
+			}
+		}
+		sources[prefix] = source
+	}
+}
+
+/*
+Effective merges the configuration files of the current program and
+mode, as Configure would load them, without a struct: First mode
+takes the first file found, Union mode merges every file found, and
+the policy file is merged last. sources maps the json key path of
+each value to the file it came from. Defaults, env and flags need the
+struct and are not applied.
+*/
+func Effective() (config map[string]any, sources map[string]Source, err error) {
+	defer Trace.ScopedTrace()()
+	config, sources = map[string]any{}, map[string]Source{}
+	var paths []string
+	switch {
+	case precedence != nil:
+		var files = precedenceFileSources()
+		for _, name := range precedence {
+			if files[name] {
+				var direct, indirect = SourceFiles(name)
+				paths = append(paths, indirect...)
+				paths = append(paths, direct...)
+			}
+		}
+	default:
+		var direct, indirect []string
+		if mode&Direct == Direct {
+			direct = DirectFiles()
+		}
+		if mode&Indirect == Indirect {
+			indirect = IndirectFiles()
+		}
+		// as Load, indirect files then direct files in Union mode and
+		// the first direct file found, else indirect, in First mode
+		paths = append(indirect, direct...)
+		if mode&First == First {
+			paths = nil
+			for _, path := range append(direct, indirect...) {
+				if exists(ExpandEnvEvalTilde(path)) {
+					paths = []string{path}
+					break
+				}
+			}
+		}
+		paths = append(paths, PolicyFile())
+	}
+	var errs []error
+	for _, path := range paths {
+		path = ExpandEnvEvalTilde(path)
+		if !exists(path) {
+			continue
+		}
+		var tree, target, rerr = readTree(path)
+		if rerr != nil {
+			errs = append(errs, rerr)
+			continue
+		}
+		var source = Source{Layer: FileLayer, Name: path}
+		if len(target) > 0 {
+			source.Name = target
+		}
+		if path == ExpandEnvEvalTilde(PolicyFile()) {
+			source = Source{Layer: PolicyLayer, Name: path, Locked: true}
+		}
+		mergeTree(config, tree, nil, sources, source)
+	}
+	return config, sources, errors.Join(errs...)
+}
@@ -0,0 +1,76 @@
+package autocfg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+func TestSearchStatusAndEffective(t *testing.T) {
+	var program, saved = Program(), GetMode()
+	homedir.DisableCache = true
+	defer func() {
+		homedir.DisableCache = false
+		SetProgram(program)
+		_, _ = SetMode(saved)
+		Reset()
+	}()
+	Reset()
+	var home = t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("AUTOCFG_FILENAME", "")
+	SetProgram("inspect-test")
+	var dir = filepath.Join(home, ".config", "inspect-test")
+	var target = filepath.Join(home, "target.json")
+	for name, text := range map[string]string{
+		filepath.Join(dir, "config.json"):  `{"role": "reader", "vault": {"addr": "a", "ttl": 5}}`,
+		filepath.Join(dir, "autocfg.json"): `{"path": "` + target + `"}`,
+		target:                             `{"vault": {"addr": "b"}}`,
+	} {
+		if err := os.MkdirAll(filepath.Dir(name), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(text), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var m, err = ParseSearchMode("union,Direct|indirect")
+	if err != nil || m != Union|Direct|Indirect {
+		t.Fatalf("expected Union-Direct-Indirect got %s %v", SearchModeName(m), err)
+	}
+	if _, err = ParseSearchMode("union-sideways"); err == nil {
+		t.Error("expected an unknown search mode error")
+	}
+	_, _ = SetMode(m)
+	var found = map[string]PathStatus{}
+	for _, status := range SearchStatus() {
+		if status.Status != StatusMissing {
+			found[status.Path] = status
+		}
+	}
+	if len(found) != 2 || found[filepath.Join(dir, "autocfg.json")].Target != target {
+		t.Errorf("expected the config and autocfg files found got %v", found)
+	}
+	var config, sources, eerr = Effective()
+	if eerr != nil {
+		t.Fatal(eerr)
+	}
+	var vault = config["vault"].(map[string]any)
+	// indirect files load before direct files in Union mode
+	if vault["addr"] != "a" || vault["ttl"] != float64(5) || config["role"] != "reader" {
+		t.Errorf("unexpected effective config %v", config)
+	}
+	if sources["vault.addr"].Name != filepath.Join(dir, "config.json") {
+		t.Errorf("expected vault.addr from config.json got %v", sources["vault.addr"])
+	}
+	if err = os.WriteFile(target, []byte(`{"vault": }`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range SearchStatus() {
+		if status.Target == target && status.Status != StatusParseError {
+			t.Errorf("expected a parse error for %s got %s", target, status)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package autocfg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ErrRequiredVar is wrapped by errors from ${VAR:?message} and
+// ${VAR?message} when VAR is unset
+var ErrRequiredVar = errors.New("required variable")
+
+// InterpolateTag names the struct tag disabling interpolation of a
+// field, e.g. `interpolate:"false"`
+const InterpolateTag = "interpolate"
+
+// Interpolation enables variable interpolation of configuration
+// string values, per file exceptions are set with SetInterpolation
+var Interpolation = true
+
+// interpolation per file overrides of Interpolation
+var interpolation = map[string]bool{}
+
+// SetInterpolation enables or disables interpolation for a
+// configuration file path
+func SetInterpolation(path string, enabled bool) {
+	defer Trace.ScopedTrace()()
+	interpolation[ExpandEnvEvalTilde(path)] = enabled
+}
+
+// interpolates reports whether the file at path is interpolated
+func interpolates(path string) bool {
+	if enabled, ok := interpolation[path]; ok {
+		return enabled
+	}
+	return Interpolation
+}
+
+/*
+Interpolate expands variables in text using the env overlay.
+
+  - $VAR and ${VAR} are replaced by the value of VAR or empty
+  - ${VAR:-default} uses default when VAR is unset or empty
+  - ${VAR-default} uses default when VAR is unset
+  - ${VAR:?message} fails with message when VAR is unset or empty
+  - ${VAR?message} fails with message when VAR is unset
+  - $$ is a literal $
+
+Defaults are themselves interpolated. Field references such as
+${.data-dir} or ${approle.mount} are left for ResolveReferences.
+*/
+func Interpolate(text string) (string, error) {
+	defer Trace.ScopedTrace()()
+	return interpolate(text, LookupEnv)
+}
+
+// interpolate text resolving variables with lookup
+func interpolate(text string, lookup func(string) (string, bool)) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		var c = text[i]
+		if c != '$' || i+1 == len(text) {
+			b.WriteByte(c)
+			continue
+		}
+		var next = text[i+1]
+		switch {
+		case next == '$':
+			// $${path} stays escaped for ResolveReferences
+			if end := closingBrace(text, i+3); i+2 < len(text) && text[i+2] == '{' && end > 0 && isReference(text[i+3:end]) {
+				b.WriteByte('$')
+			}
+			b.WriteByte('$')
+			i++
+		case next == '{':
+			var end = closingBrace(text, i+2)
+			if end < 0 {
+				return "", fmt.Errorf("unterminated ${ in %q", text)
+			}
+			if isReference(text[i+2 : end]) {
+				// field references resolve after all layers apply
+				b.WriteString(text[i : end+1])
+				i = end
+				continue
+			}
+			var value, err = expand(text[i+2:end], lookup)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+			i = end
+		case isNameByte(next, true):
+			var j = i + 1
+			for j < len(text) && isNameByte(text[j], false) {
+				j++
+			}
+			var value, _ = lookup(text[i+1 : j])
+			b.WriteString(value)
+			i = j - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+// closingBrace index of the } matching the ${ opened before start
+func closingBrace(text string, start int) int {
+	var depth = 1
+	for i := start; i < len(text); i++ {
+		switch text[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isNameByte(c byte, first bool) bool {
+	return c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || !first && c >= '0' && c <= '9'
+}
+
+// expand the body of a ${...} expression
+func expand(expr string, lookup func(string) (string, bool)) (value string, err error) {
+	var name = expr
+	var op, arg string
+	if i := strings.IndexAny(expr, ":-?"); i >= 0 {
+		name = expr[:i]
+		op = expr[i : i+1]
+		arg = expr[i+1:]
+		if op == ":" && len(arg) > 0 && (arg[0] == '-' || arg[0] == '?') {
+			op += arg[:1]
+			arg = arg[1:]
+		}
+	}
+	var ok bool
+	value, ok = lookup(name)
+	switch op {
+	case "":
+	case ":-", "-":
+		if !ok || op == ":-" && len(value) == 0 {
+			value, err = interpolate(arg, lookup)
+		}
+	case ":?", "?":
+		if !ok || op == ":?" && len(value) == 0 {
+			if len(arg) == 0 {
+				arg = "not set"
+			}
+			err = fmt.Errorf("%w %s: %s", ErrRequiredVar, name, arg)
+		}
+	default:
+		err = fmt.Errorf("invalid expression ${%s}", expr)
+	}
+	return
+}
+
+// interpolateJSON expands variables in the string values of JSON
+// text, leaving keys, numbers and structure alone. Fields of obj
+// tagged `interpolate:"false"` keep their text verbatim.
+func interpolateJSON(text []byte, obj any) ([]byte, error) {
+	defer Trace.ScopedTrace()()
+	var tree any
+	var decoder = json.NewDecoder(bytes.NewReader(text))
+	decoder.UseNumber()
+	if err := decoder.Decode(&tree); err != nil {
+		// leave syntax errors for the decode of obj to report
+		return text, nil
+	}
+	var err error
+	if tree, err = interpolateTree(tree, reflect.TypeOf(obj), ""); err != nil {
+		return text, err
+	}
+	return json.Marshal(tree)
+}
+
+// interpolateTree walks a decoded JSON value alongside the Go type
+// it decodes into
+func interpolateTree(node any, t reflect.Type, at string) (any, error) {
+	t = elemType(t)
+	var err error
+	switch v := node.(type) {
+	case string:
+		var s string
+		if s, err = Interpolate(v); err != nil {
+			return nil, fmt.Errorf("%s: %w", strings.TrimPrefix(at, "."), err)
+		}
+		return s, nil
+	case []any:
+		var elem reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			elem = t.Elem()
+		}
+		for i := range v {
+			if v[i], err = interpolateTree(v[i], elem, at+"["+strconv.Itoa(i)+"]"); err != nil {
+				return nil, err
+			}
+		}
+	case map[string]any:
+		for key, child := range v {
+			var ct reflect.Type
+			if t != nil && t.Kind() == reflect.Struct {
+				if sf, ok := lookupField(t, key); ok {
+					if verbatim(sf) {
+						continue
+					}
+					ct = sf.Type
+				}
+			} else if t != nil && t.Kind() == reflect.Map {
+				ct = t.Elem()
+			}
+			if v[key], err = interpolateTree(child, ct, at+"."+key); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return node, nil
+}
+
+// verbatim reports a field tagged `interpolate:"false"`
+func verbatim(sf reflect.StructField) bool {
+	if text, ok := sf.Tag.Lookup(InterpolateTag); ok {
+		var enabled, err = strconv.ParseBool(text)
+		return err == nil && !enabled
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package autocfg
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestInterpolate(t *testing.T) {
+	defer ResetEnv()
+	Setenv("AUTOCFG_TEST_SET", "value", false)
+	Setenv("AUTOCFG_TEST_EMPTY", "", false)
+	var tests = []struct {
+		text, want string
+		err        error
+	}{
+		{"plain", "plain", nil},
+		{"$AUTOCFG_TEST_SET/${AUTOCFG_TEST_SET}", "value/value", nil},
+		{"pa$$word", "pa$word", nil},
+		{"${AUTOCFG_TEST_UNSET:-fallback}", "fallback", nil},
+		{"${AUTOCFG_TEST_EMPTY:-fallback}", "fallback", nil},
+		{"${AUTOCFG_TEST_EMPTY-fallback}", "", nil},
+		{"${AUTOCFG_TEST_UNSET:-${AUTOCFG_TEST_SET}}", "value", nil},
+		{"${AUTOCFG_TEST_UNSET:?must be set}", "", ErrRequiredVar},
+		{"${AUTOCFG_TEST_SET:?must be set}", "value", nil},
+		{"trailing $", "trailing $", nil},
+		{"${.data-dir}/logs", "${.data-dir}/logs", nil},
+		{"auth/$${approle.mount}", "auth/$${approle.mount}", nil},
+	}
+	for _, test := range tests {
+		var got, err = Interpolate(test.text)
+		if !errors.Is(err, test.err) {
+			t.Errorf("Interpolate(%q) error %v want %v", test.text, err, test.err)
+			continue
+		}
+		if err == nil && got != test.want {
+			t.Errorf("Interpolate(%q) = %q want %q", test.text, got, test.want)
+		}
+	}
+}
+
+func TestExpandEnvEvalTilde(t *testing.T) {
+	defer ResetEnv()
+	ResetWarnings()
+	Setenv("AUTOCFG_TEST_SET", "value", false)
+	if path := ExpandEnvEvalTilde("/etc/${AUTOCFG_TEST_SET}/config.json"); path != "/etc/value/config.json" {
+		t.Errorf("expected the path expanded got %q", path)
+	}
+	if path := ExpandEnvEvalTilde("/etc/${AUTOCFG_TEST_UNSET:?unset}/config.json"); path != "" {
+		t.Errorf("expected the path skipped got %q", path)
+	}
+	if len(Warnings()) != 1 || !strings.Contains(Warnings()[0], "AUTOCFG_TEST_UNSET") {
+		t.Errorf("expected a warning naming the path got %q", Warnings())
+	}
+}
+
+func TestInterpolateJSON(t *testing.T) {
+	defer ResetEnv()
+	Setenv("AUTOCFG_TEST_SET", "value", false)
+	type conf struct {
+		Role   string `json:"role"`
+		Secret string `json:"secret" interpolate:"false"`
+	}
+	var text, err = interpolateJSON([]byte(`{"role": "${AUTOCFG_TEST_SET}", "secret": "$AUTOCFG_TEST_SET"}`), &conf{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(text) != `{"role":"value","secret":"$AUTOCFG_TEST_SET"}` {
+		t.Errorf("unexpected interpolation %s", text)
+	}
+}
@@ -0,0 +1,182 @@
+package autocfg
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// JSONC accepts comments, trailing commas, unquoted keys and single
+// quoted strings in .json files too, .jsonc and .json5 files always
+// accept them
+var JSONC bool
+
+// isJSONC reports whether the file at path is read as JSONC
+func isJSONC(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonc", ".json5":
+		return true
+	}
+	return JSONC
+}
+
+// standardJSON of the text of the file at path, standardized when the
+// file is JSONC and converted when it is YAML, with the origin of
+// offsets, nil when unchanged
+func standardJSON(path string, text []byte) ([]byte, func(int64) int64, error) {
+	if TemplateFormat(path) == FormatYAML {
+		return yamlJSON(path, text)
+	}
+	if isJSONC(path) {
+		var out, origin = standardize(text)
+		return out, origin, nil
+	}
+	return text, nil, nil
+}
+
+// edit records the change in length of standardized text, from offset
+// at on delta is added to a standard offset to find the source offset
+type edit struct {
+	at, delta int64
+}
+
+// standardize converts JSONC and the JSON5 forms below to JSON
+//
+//   - // line and /* block */ comments
+//   - trailing commas in objects and arrays
+//   - unquoted identifier keys, which may contain hyphens
+//   - 'single quoted' strings
+//
+// Comments and trailing commas become spaces and newlines are kept so
+// lines match the source. origin maps an offset in the JSON to the
+// source offset for error positions.
+func standardize(text []byte) (out []byte, origin func(int64) int64) {
+	out = make([]byte, 0, len(text)+len(text)/8)
+	var edits []edit
+	var delta int64
+	var shift = func(n int64) {
+		delta -= n
+		edits = append(edits, edit{at: int64(len(out)), delta: delta})
+	}
+	origin = func(offset int64) int64 {
+		var d int64
+		for _, e := range edits {
+			if e.at > offset {
+				break
+			}
+			d = e.delta
+		}
+		return offset + d
+	}
+	for i := 0; i < len(text); i++ {
+		var c = text[i]
+		switch {
+		case c == '"':
+			var end = stringEnd(text, i, '"')
+			out = append(out, text[i:end]...)
+			i = end - 1
+		case c == '\'':
+			out = append(out, '"')
+			for i++; i < len(text) && text[i] != '\''; i++ {
+				switch {
+				case text[i] == '\\' && i+1 < len(text) && text[i+1] == '\'':
+					out = append(out, '\'')
+					i++
+					shift(-1)
+				case text[i] == '\\' && i+1 < len(text):
+					out = append(out, text[i], text[i+1])
+					i++
+				case text[i] == '"':
+					out = append(out, '\\', '"')
+					shift(1)
+				default:
+					out = append(out, text[i])
+				}
+			}
+			out = append(out, '"')
+		case c == '/' && i+1 < len(text) && (text[i+1] == '/' || text[i+1] == '*'):
+			var end = commentEnd(text, i)
+			for _, b := range text[i:end] {
+				if b == '\n' || b == '\r' {
+					out = append(out, b)
+					continue
+				}
+				out = append(out, ' ')
+			}
+			i = end - 1
+		case c == ',':
+			if next := significant(text, i+1); next < len(text) && (text[next] == '}' || text[next] == ']') {
+				out = append(out, ' ')
+				continue
+			}
+			out = append(out, c)
+		case isIdentStart(c):
+			var end = i
+			for end < len(text) && (isIdentStart(text[end]) || text[end] == '-' || text[end] >= '0' && text[end] <= '9') {
+				end++
+			}
+			if next := significant(text, end); next < len(text) && text[next] == ':' {
+				out = append(out, '"')
+				shift(1)
+				out = append(out, text[i:end]...)
+				out = append(out, '"')
+				shift(1)
+			} else {
+				out = append(out, text[i:end]...)
+			}
+			i = end - 1
+		default:
+			out = append(out, c)
+		}
+	}
+	return
+}
+
+// stringEnd is the offset after the quote closing the string at start
+func stringEnd(text []byte, start int, quote byte) int {
+	for i := start + 1; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1
+		}
+	}
+	return len(text)
+}
+
+// commentEnd is the offset after the comment at start
+func commentEnd(text []byte, start int) int {
+	if text[start+1] == '/' {
+		for i := start; i < len(text); i++ {
+			if text[i] == '\n' {
+				return i
+			}
+		}
+		return len(text)
+	}
+	for i := start + 2; i+1 < len(text); i++ {
+		if text[i] == '*' && text[i+1] == '/' {
+			return i + 2
+		}
+	}
+	return len(text)
+}
+
+// significant is the offset of the next byte that is not white space
+// or part of a comment
+func significant(text []byte, start int) int {
+	for i := start; i < len(text); i++ {
+		switch {
+		case text[i] == ' ' || text[i] == '\t' || text[i] == '\r' || text[i] == '\n':
+		case text[i] == '/' && i+1 < len(text) && (text[i+1] == '/' || text[i+1] == '*'):
+			i = commentEnd(text, i) - 1
+		default:
+			return i
+		}
+	}
+	return len(text)
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,54 @@
+package autocfg
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type jsoncConf struct {
+	Role  string `json:"role"`
+	Mount string `json:"mount"`
+	Vault string `json:"vault-version"`
+	Port  int    `json:"port"`
+}
+
+func TestLoadJSONC(t *testing.T) {
+	defer Reset()
+	Reset()
+	var dir = t.TempDir()
+	var file = filepath.Join(dir, "config.jsonc")
+	var text = `{
+  // the role vault grants
+  role: 'reader "ro"',
+  /* the approle
+     mount path */
+  "mount": "approle",
+  vault-version: "1.14", // trailing comma next
+}
+`
+	if err := os.WriteFile(file, []byte(text), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var o = &jsoncConf{}
+	if err := LoadDirect(file, o); err != nil {
+		t.Fatal(err)
+	}
+	if o.Role != `reader "ro"` || o.Mount != "approle" || o.Vault != "1.14" {
+		t.Errorf("unexpected values %+v", o)
+	}
+
+	file = filepath.Join(dir, "bad.json5")
+	text = "{\n  /* port */ port: 'eighty',\n}\n"
+	if err := os.WriteFile(file, []byte(text), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var pe *ParseError
+	if err := LoadDirect(file, o); !errors.As(err, &pe) {
+		t.Fatalf("expected ParseError got %v", err)
+	}
+	if pe.Line != 2 || pe.Column != 20 || pe.Excerpt != "\t  /* port */ port: 'eighty',\n\t                   ^" {
+		t.Errorf("expected 2:20 got %d:%d\n%s", pe.Line, pe.Column, pe.Excerpt)
+	}
+}
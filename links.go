@@ -0,0 +1,239 @@
+package autocfg
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	eflag "github.com/davidwalter0/go-flag"
+)
+
+// link flags of a multicall program, run ahead of any call
+const (
+	InstallLinksFlag = "install-links"
+	VerifyLinksFlag  = "verify-links"
+	RemoveLinksFlag  = "remove-links"
+	DryRunFlag       = "dry-run"
+)
+
+// LinkMode of InstallLinks
+type LinkMode int
+
+// link modes
+const (
+	// LinkInstall creates the missing call links
+	LinkInstall LinkMode = iota
+	// LinkVerify reports missing or conflicting call links
+	LinkVerify
+	// LinkRemove removes the call links to the executable
+	LinkRemove
+)
+
+// call link status values
+const (
+	LinkCreated  = "created"
+	LinkOK       = "ok"
+	LinkMissing  = "missing"
+	LinkRemoved  = "removed"
+	LinkConflict = "conflict"
+	// LinkStale is a dangling link, its target no longer exists
+	LinkStale = "stale"
+)
+
+// LinkStatus of the symlink of a call
+type LinkStatus struct {
+	Call   string `json:"call"`
+	Path   string `json:"path"`
+	Target string `json:"target,omitempty"`
+	Status string `json:"status"`
+	// DryRun statuses report the change without making it
+	DryRun bool   `json:"dry-run,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// String formats the status as "status path -> target: error"
+func (status LinkStatus) String() (text string) {
+	text = fmt.Sprintf("%-8s %s", status.Status, status.Path)
+	if status.DryRun {
+		text = "dry-run " + text
+	}
+	if len(status.Target) > 0 {
+		text += " -> " + status.Target
+	}
+	if len(status.Error) > 0 {
+		text += ": " + status.Error
+	}
+	return
+}
+
+// executable path with symlinks resolved, the target of call links
+func executable() (path string, err error) {
+	if path, err = os.Executable(); err != nil {
+		return
+	}
+	return filepath.EvalSymlinks(path)
+}
+
+/*
+InstallLinks creates, verifies or removes a symlink in dir named for
+each registered call pointing at the executable, for dispatch by
+program name. Links in place are left as is, so it is idempotent. A
+file or a link to another target is a conflict, never replaced or
+removed. A stale link, left dangling to an executable of the same
+name after it moved, is replaced on install, reported on verify and
+removed. With dryRun the statuses report the changes without making
+them. The error joins the conflicts, the missing links when
+verifying and the failed changes.
+
+	ex-app --install-links /usr/local/bin --dry-run
+*/
+func (m *Multicall) InstallLinks(dir string, mode LinkMode, dryRun bool) (list []LinkStatus, err error) {
+	defer Trace.ScopedTrace()()
+	var target string
+	if target, err = executable(); err != nil {
+		return
+	}
+	var expanded = ExpandEnvEvalTilde(dir)
+	if len(expanded) == 0 {
+		// never the current directory in place of a bad dir
+		return nil, fmt.Errorf("link dir %q %w", dir, fs.ErrInvalid)
+	}
+	if dir, err = filepath.Abs(expanded); err != nil {
+		return
+	}
+	if mode == LinkInstall && !dryRun {
+		if err = os.MkdirAll(dir, 0755); err != nil {
+			return
+		}
+	}
+	var errs []error
+	for _, call := range m.calls {
+		var status = linkCall(call.Name, filepath.Join(dir, call.Name), target, mode, dryRun)
+		if len(status.Error) > 0 {
+			errs = append(errs, fmt.Errorf("%s %s", status.Path, status.Error))
+		}
+		list = append(list, status)
+	}
+	return list, errors.Join(errs...)
+}
+
+// linkCall applies mode to the link of one call
+func linkCall(name, path, target string, mode LinkMode, dryRun bool) (status LinkStatus) {
+	status = LinkStatus{Call: name, Path: path, Target: target}
+	var info, err = os.Lstat(path)
+	switch {
+	case os.IsNotExist(err):
+		err = nil
+		status.Status = LinkMissing
+		switch mode {
+		case LinkInstall:
+			status.Status, status.DryRun = LinkCreated, dryRun
+			if !dryRun {
+				err = os.Symlink(target, path)
+			}
+		case LinkVerify:
+			status.Error = "link " + LinkMissing
+		}
+	case err != nil:
+	case info.Mode()&os.ModeSymlink == 0:
+		status.Status, status.Target = LinkConflict, ""
+		status.Error = "is not a link"
+	case stale(path, target):
+		status.Status = LinkStale
+		status.Target, _ = os.Readlink(path)
+		switch mode {
+		case LinkInstall:
+			status.Status, status.Target, status.DryRun = LinkCreated, target, dryRun
+			if !dryRun {
+				if err = os.Remove(path); err == nil {
+					err = os.Symlink(target, path)
+				}
+			}
+		case LinkVerify:
+			status.Error = "link " + LinkStale
+		case LinkRemove:
+			status.Status, status.DryRun = LinkRemoved, dryRun
+			if !dryRun {
+				err = os.Remove(path)
+			}
+		}
+	default:
+		if resolved, rerr := filepath.EvalSymlinks(path); rerr != nil || resolved != target {
+			status.Status = LinkConflict
+			status.Target, _ = os.Readlink(path)
+			status.Error = "links to another target"
+			return
+		}
+		status.Status = LinkOK
+		if mode == LinkRemove {
+			status.Status, status.DryRun = LinkRemoved, dryRun
+			if !dryRun {
+				err = os.Remove(path)
+			}
+		}
+	}
+	if err != nil {
+		status.Error = err.Error()
+	}
+	return
+}
+
+// stale reports a dangling link to a file named as target, a link
+// of the executable before it moved, a dangling link of another tool
+// is a conflict
+func stale(path, target string) bool {
+	var link, err = os.Readlink(path)
+	if err != nil || filepath.Base(link) != filepath.Base(target) {
+		return false
+	}
+	_, err = filepath.EvalSymlinks(path)
+	return errors.Is(err, fs.ErrNotExist)
+}
+
+// isLinkFlag reports an argument naming one of the link flags
+func isLinkFlag(arg string) bool {
+	if !strings.HasPrefix(arg, "-") {
+		return false
+	}
+	var name, _, _ = strings.Cut(strings.TrimLeft(arg, "-"), "=")
+	switch name {
+	case InstallLinksFlag, VerifyLinksFlag, RemoveLinksFlag, DryRunFlag:
+		return true
+	}
+	return false
+}
+
+// installLinks runs the link flags of a multicall program, in any
+// order, args start with one of them
+func (m *Multicall) installLinks(program string, args []string) (err error) {
+	var dir string
+	var verify, remove, dryRun bool
+	var flags = eflag.NewFlagSet(program+" --"+InstallLinksFlag, eflag.ContinueOnError)
+	flags.StringVar(&dir, InstallLinksFlag, "", "directory of the call links", false, false)
+	flags.BoolVar(&verify, VerifyLinksFlag, false, "report missing or conflicting links without changes", false, false)
+	flags.BoolVar(&remove, RemoveLinksFlag, false, "remove the links to the executable", false, false)
+	flags.BoolVar(&dryRun, DryRunFlag, false, "report the changes without making them", false, false)
+	if err = flags.Parse(args); err != nil {
+		return
+	}
+	var mode = LinkInstall
+	switch {
+	case len(dir) == 0:
+		return fmt.Errorf("--%s: directory is empty", InstallLinksFlag)
+	case verify && remove:
+		return fmt.Errorf("--%s and --%s are exclusive", VerifyLinksFlag, RemoveLinksFlag)
+	case verify:
+		mode = LinkVerify
+	case remove:
+		mode = LinkRemove
+	}
+	var list []LinkStatus
+	list, err = m.InstallLinks(dir, mode, dryRun)
+	for _, status := range list {
+		fmt.Fprintln(PrintOutput, status)
+	}
+	return
+}
@@ -0,0 +1,112 @@
+package autocfg
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInstallLinks(t *testing.T) {
+	var output = PrintOutput
+	defer func() { PrintOutput = output }()
+	var m = NewMulticall("links-test")
+	var run = func([]string) error { return nil }
+	for _, name := range []string{"login", "lookup"} {
+		if err := m.Register(Call{Name: name, Run: run}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var dir = filepath.Join(t.TempDir(), "bin")
+	var statuses = func(list []LinkStatus) (text []string) {
+		for _, status := range list {
+			text = append(text, status.Call+" "+status.Status)
+		}
+		return
+	}
+
+	var list, err = m.InstallLinks(dir, LinkInstall, true)
+	if err != nil || strings.Join(statuses(list), ",") != "login created,lookup created" || !list[0].DryRun {
+		t.Fatalf("unexpected dry run %v %v", list, err)
+	}
+	if _, err = os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatalf("expected no changes in a dry run got %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if list, err = m.InstallLinks(dir, LinkInstall, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if strings.Join(statuses(list), ",") != "login ok,lookup ok" {
+		t.Errorf("expected the links in place on a second install got %v", list)
+	}
+	var target, _ = executable()
+	if resolved, err := filepath.EvalSymlinks(filepath.Join(dir, "login")); err != nil || resolved != target {
+		t.Errorf("expected login linked to %s got %s %v", target, resolved, err)
+	}
+
+	if err = os.Remove(filepath.Join(dir, "lookup")); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, "lookup"), []byte("other"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	list, err = m.InstallLinks(dir, LinkRemove, false)
+	if err == nil || strings.Join(statuses(list), ",") != "login removed,lookup conflict" {
+		t.Errorf("expected login removed and a lookup conflict got %v %v", list, err)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "lookup")); err != nil {
+		t.Errorf("expected the conflicting file kept %v", err)
+	}
+	list, err = m.InstallLinks(dir, LinkVerify, false)
+	if err == nil || strings.Join(statuses(list), ",") != "login missing,lookup conflict" {
+		t.Errorf("expected login missing got %v %v", list, err)
+	}
+
+	// a dangling link of another tool is a conflict, never removed
+	if err = os.Symlink(filepath.Join(dir, "other-tool"), filepath.Join(dir, "login")); err != nil {
+		t.Fatal(err)
+	}
+	list, _ = m.InstallLinks(dir, LinkRemove, false)
+	if strings.Join(statuses(list), ",") != "login conflict,lookup conflict" {
+		t.Errorf("expected a dangling link of another tool a conflict got %v", list)
+	}
+	if _, err = os.Lstat(filepath.Join(dir, "login")); err != nil {
+		t.Errorf("expected the other tool link kept got %v", err)
+	}
+	if err = os.Remove(filepath.Join(dir, "login")); err != nil {
+		t.Fatal(err)
+	}
+	// a link left dangling by a moved executable is stale, not a conflict
+	if err = os.Symlink(filepath.Join(dir, "moved", filepath.Base(target)), filepath.Join(dir, "login")); err != nil {
+		t.Fatal(err)
+	}
+	list, _ = m.InstallLinks(dir, LinkVerify, false)
+	if strings.Join(statuses(list), ",") != "login stale,lookup conflict" {
+		t.Errorf("expected a stale login link got %v", list)
+	}
+	if list, _ = m.InstallLinks(dir, LinkRemove, false); list[0].Status != LinkRemoved {
+		t.Errorf("expected the stale login link removed got %v", list)
+	}
+	if _, err = os.Lstat(filepath.Join(dir, "login")); !os.IsNotExist(err) {
+		t.Errorf("expected the stale link gone got %v", err)
+	}
+	if _, err = m.InstallLinks("${AUTOCFG_TEST_UNSET:?unset}", LinkInstall, false); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("expected a link dir that does not expand rejected got %v", err)
+	}
+	if err = m.Register(Call{Name: "sub/login", Run: run}); err == nil {
+		t.Errorf("expected a call name with a slash rejected")
+	}
+
+	var buffer bytes.Buffer
+	PrintOutput = &buffer
+	if err = m.Dispatch([]string{"links-test", "--dry-run", "--install-links", dir}); err == nil {
+		t.Errorf("expected the lookup conflict from dispatch")
+	}
+	if !strings.Contains(buffer.String(), "dry-run created  "+filepath.Join(dir, "login")) {
+		t.Errorf("unexpected dispatch output\n%s", buffer.String())
+	}
+}
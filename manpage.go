@@ -0,0 +1,222 @@
+package autocfg
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	eflag "github.com/davidwalter0/go-flag"
+	"github.com/mitchellh/go-homedir"
+)
+
+// option of a reference page, one per field
+type option struct {
+	Key     string
+	Flag    string
+	Env     string
+	Type    string
+	Default string
+	Doc     string
+	Values  []string
+}
+
+// options of obj in field order. Flag and env names are those defined
+// when Configure or a multicall variant has run for obj, else the
+// go-cfg Add names. Fields a sources tag keeps from a layer have no
+// name for it.
+func options(obj any) (list []option) {
+	var flags = map[string]string{}
+	for name, l := range flagLeaves(obj) {
+		if !hidden[name] {
+			flags[l.Key()] = name
+		}
+	}
+	for _, l := range leaves(obj) {
+		var o = option{
+			Key:     l.Key(),
+			Type:    l.Field.Type.String(),
+			Default: l.Field.Tag.Get("default"),
+			Doc:     strings.Join(strings.Fields(docText(l.Field)), " "),
+		}
+		if c, err := constraints(l.Field); err == nil {
+			o.Values = c.Enum
+		}
+		var name = cfgFlagName(l.Path[len(l.Path)-1:])
+		if defined, ok := flags[l.Key()]; ok {
+			name = defined
+		}
+		if allowed(l.Field, FlagLayer) {
+			o.Flag = name
+		}
+		if allowed(l.Field, EnvLayer) {
+			switch {
+			case envPolicy != nil:
+				o.Env = envPolicy.Name(l.Path)
+			default:
+				o.Env = cfgEnvName(l.Path[len(l.Path)-1:])
+				if flag := eflag.Lookup(name); flag != nil && len(flagEnvName(flag)) > 0 {
+					o.Env = flagEnvName(flag)
+				}
+			}
+		}
+		list = append(list, o)
+	}
+	return
+}
+
+// searchFiles lists the configuration files of the search mode with a
+// description of each, the policy file last
+func searchFiles() (files [][2]string) {
+	if mode&Direct == Direct || precedence != nil {
+		for _, path := range DirectFiles() {
+			files = append(files, [2]string{displayPath(path), "configuration file"})
+		}
+	}
+	if mode&Indirect == Indirect || precedence != nil {
+		for _, path := range IndirectFiles() {
+			files = append(files, [2]string{displayPath(path), "autocfg file naming the configuration file to load"})
+		}
+	}
+	return append(files, [2]string{displayPath(PolicyFile()), "policy file, enforced last, the fields it sets are locked"})
+}
+
+// displayPath writes the home directory as ~ and the current directory
+// as a relative path, as the search paths read on any host
+func displayPath(path string) string {
+	path = strings.Replace(path, "${HOME}", "~", 1)
+	if home, err := homedir.Dir(); err == nil && strings.HasPrefix(path, home+"/") {
+		path = "~" + strings.TrimPrefix(path, home)
+	}
+	if dir, err := os.Getwd(); err == nil && strings.HasPrefix(path, dir+"/") {
+		path = strings.TrimPrefix(path, dir+"/")
+	}
+	return path
+}
+
+// environment variables of autocfg itself with a description of each
+func environment() (vars [][2]string) {
+	vars = append(vars, [2]string{"AUTOCFG_FILENAME",
+		"configuration or autocfg file searched ahead of the program files, see FILES"})
+	if Dotenv {
+		vars = append(vars, [2]string{"AUTOCFG_PROFILE",
+			"profile selecting .env.{{profile}} dotenv files"})
+	}
+	return
+}
+
+var roffEscape = strings.NewReplacer(`\`, `\e`, "-", `\-`)
+
+// roff escapes text and protects a leading control character
+func roff(text string) string {
+	text = roffEscape.Replace(text)
+	if strings.HasPrefix(text, ".") || strings.HasPrefix(text, "'") {
+		text = `\&` + text
+	}
+	return text
+}
+
+/*
+WriteManPage writes a roff man page, section 1, of the configuration
+of obj: each field with its flag, env var, file key path, type,
+default and doc tag, the FILES the search mode reads for the program
+and the autocfg ENVIRONMENT variables. Call it after Configure to use
+the flag names Configure defined.
+
+	man ./ex-app.1
+*/
+func WriteManPage(w io.Writer, obj any) (err error) {
+	defer Trace.ScopedTrace()()
+	if !isPtr(obj) {
+		return fmt.Errorf("arg obj any [%T]: object is not a pointer to struct", obj)
+	}
+	var buffer bytes.Buffer
+	var name = roff(pgm)
+	fmt.Fprintf(&buffer, ".TH %s 1\n.SH NAME\n%s \\- %s configuration\n", strings.ToUpper(name), name, name)
+	fmt.Fprintf(&buffer, ".SH SYNOPSIS\n.B %s\n[\\fIoptions\\fR]\n", name)
+	buffer.WriteString(".SH OPTIONS\n")
+	for _, o := range options(obj) {
+		buffer.WriteString(".TP\n")
+		if len(o.Flag) > 0 {
+			fmt.Fprintf(&buffer, "\\fB\\-\\-%s\\fR \\fI%s\\fR\n", roff(o.Flag), roff(o.Type))
+		} else {
+			fmt.Fprintf(&buffer, "\\fB%s\\fR \\fI%s\\fR\n", roff(o.Key), roff(o.Type))
+		}
+		if len(o.Doc) > 0 {
+			fmt.Fprintf(&buffer, "%s\n.br\n", roff(o.Doc))
+		}
+		var details = []string{"key " + o.Key}
+		if len(o.Env) > 0 {
+			details = append(details, "env "+o.Env)
+		}
+		if len(o.Flag) == 0 {
+			details = append(details, "no flag")
+		}
+		if len(o.Default) > 0 {
+			details = append(details, "default "+o.Default)
+		}
+		if len(o.Values) > 0 {
+			details = append(details, "one of "+strings.Join(o.Values, ", "))
+		}
+		fmt.Fprintf(&buffer, "%s\n", roff(strings.Join(details, "; ")))
+	}
+	buffer.WriteString(".SH FILES\n")
+	for _, file := range searchFiles() {
+		fmt.Fprintf(&buffer, ".TP\n.I %s\n%s\n", roff(file[0]), roff(file[1]))
+	}
+	buffer.WriteString(".SH ENVIRONMENT\n")
+	for _, v := range environment() {
+		fmt.Fprintf(&buffer, ".TP\n.B %s\n%s\n", roff(v[0]), roff(v[1]))
+	}
+	_, err = w.Write(buffer.Bytes())
+	return
+}
+
+var cellEscape = strings.NewReplacer("|", `\|`, "\n", " ")
+
+// cell of a markdown table, code spans for names
+func cell(text string, code bool) string {
+	if len(text) == 0 {
+		return ""
+	}
+	if code {
+		return "`" + cellEscape.Replace(text) + "`"
+	}
+	return cellEscape.Replace(text)
+}
+
+// WriteReference writes a Markdown reference of the configuration of
+// obj with the same content as WriteManPage
+func WriteReference(w io.Writer, obj any) (err error) {
+	defer Trace.ScopedTrace()()
+	if !isPtr(obj) {
+		return fmt.Errorf("arg obj any [%T]: object is not a pointer to struct", obj)
+	}
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, "# %s configuration\n\n## Options\n\n", pgm)
+	buffer.WriteString("| Flag | Env | Key | Type | Default | Description |\n")
+	buffer.WriteString("|------|-----|-----|------|---------|-------------|\n")
+	for _, o := range options(obj) {
+		var flag string
+		if len(o.Flag) > 0 {
+			flag = "--" + o.Flag
+		}
+		var doc = o.Doc
+		if len(o.Values) > 0 {
+			doc = strings.TrimSpace(doc + " (one of " + strings.Join(o.Values, ", ") + ")")
+		}
+		fmt.Fprintf(&buffer, "| %s | %s | %s | %s | %s | %s |\n", cell(flag, true), cell(o.Env, true),
+			cell(o.Key, true), cell(o.Type, true), cell(o.Default, true), cell(doc, false))
+	}
+	buffer.WriteString("\n## Files\n\n")
+	for _, file := range searchFiles() {
+		fmt.Fprintf(&buffer, "- `%s` %s\n", file[0], file[1])
+	}
+	buffer.WriteString("\n## Environment\n\n")
+	for _, v := range environment() {
+		fmt.Fprintf(&buffer, "- `%s` %s\n", v[0], v[1])
+	}
+	_, err = w.Write(buffer.Bytes())
+	return
+}
@@ -0,0 +1,61 @@
+package autocfg
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+type manConf struct {
+	Role   string `json:"role" doc:"approle role id" default:"reader" enum:"reader,admin"`
+	Secret string `json:"secret" sources:"file,env"`
+	Vault  struct {
+		Addr string `json:"addr" doc:"vault address"`
+	} `json:"vault"`
+}
+
+func TestWriteManPage(t *testing.T) {
+	var args, saved = os.Args, GetMode()
+	defer func() {
+		os.Args = args
+		_, _ = SetMode(saved)
+		Reset()
+	}()
+	Reset()
+	os.Args = []string{"autocfg.test"}
+	_, _ = SetMode(Union | Direct | Indirect)
+	var obj = &manConf{}
+	Configure(obj)
+	var buffer bytes.Buffer
+	if err := WriteManPage(&buffer, obj); err != nil {
+		t.Fatal(err)
+	}
+	var name = roff(Program())
+	for _, want := range []string{
+		".SH NAME\n" + name + ` \- ` + name + " configuration\n",
+		".TP\n\\fB\\-\\-role\\fR \\fIstring\\fR\napprole role id\n.br\nkey role; env ROLE; default reader; one of reader, admin\n",
+		".TP\n\\fBsecret\\fR \\fIstring\\fR\nkey secret; env SECRET; no flag\n",
+		".TP\n.I ~/.config/" + name + "/config.json\nconfiguration file\n",
+		".TP\n.I ~/.config/" + name + "/autocfg.json\nautocfg file",
+		".SH ENVIRONMENT\n.TP\n.B AUTOCFG_FILENAME\n",
+	} {
+		if !strings.Contains(buffer.String(), want) {
+			t.Errorf("expected %q in\n%s", want, buffer.String())
+		}
+	}
+	buffer.Reset()
+	if err := WriteReference(&buffer, obj); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"| `--role` | `ROLE` | `role` | `string` | `reader` | approle role id (one of reader, admin) |\n",
+		"|  | `SECRET` | `secret` | `string` |  |  |\n",
+		"| `--addr` | `ADDR` | `vault.addr` | `string` |  | vault address |\n",
+		"- `~/.config/" + Program() + "/config.json` configuration file\n",
+	} {
+		if !strings.Contains(buffer.String(), want) {
+			t.Errorf("expected %q in\n%s", want, buffer.String())
+		}
+	}
+}
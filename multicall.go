@@ -0,0 +1,425 @@
+package autocfg
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	eflag "github.com/davidwalter0/go-flag"
+)
+
+// HelpCall names the call listing the registered calls, or printing
+// the flags of a call with help NAME
+const HelpCall = "help"
+
+// ErrUnknownCall is returned by Dispatch when no registered call
+// matches the program name or the first argument
+var ErrUnknownCall = errors.New("unknown call")
+
+// Call of a multicall program
+type Call struct {
+	// Name of the call, the symlink name or first argument
+	Name string
+	// Description of the call in the usage of the program
+	Description string
+	// Config is a pointer to the struct configured for the call, read
+	// from the section of the call in the configuration files, its
+	// fields and the Shared fields are the only flags of the call
+	Config any
+	// Section is the json key of the call section, the Name when empty
+	Section string
+	// Run the call with the arguments left after the flags
+	Run func(args []string) error
+}
+
+/*
+Multicall registers the calls of a busybox style program dispatched
+by the name it is run as, a symlink to the program, or by its first
+argument. Each call reads the top level Shared fields and the section
+of the call, its Section or name, from the program files.
+
+	{"vault-address": "https://vault:8200", "approle": {"role": "reader"}}
+
+	var m = autocfg.NewMulticall("")
+	m.Shared = &options
+	m.Register(autocfg.Call{Name: "approle", Config: &approle, Run: login})
+	if err := m.Dispatch(os.Args); err != nil {
+		log.Fatal(err)
+	}
+*/
+type Multicall struct {
+	// Name of the program, in search paths and usage, defaults to the
+	// executable name so a symlinked call reads the program files
+	Name string
+	// Shared is a pointer to the struct of the top level fields every
+	// call reads, nil for none
+	Shared any
+	// CallFiles adds ${HOME}/.config/{{program}}/{{call}}.json to the
+	// files a call reads, with the Shared and section fields top level
+	CallFiles bool
+	// Configure configures the view of a call, Configure by default
+	Configure func(obj any) error
+	calls     []*Call
+}
+
+// NewMulticall returns an empty registry for the program name, the
+// executable name when empty
+func NewMulticall(name string) *Multicall {
+	defer Trace.ScopedTrace()()
+	return &Multicall{Name: name, Configure: Configure}
+}
+
+// Register a call, names are unique
+func (m *Multicall) Register(call Call) (err error) {
+	defer Trace.ScopedTrace()()
+	switch {
+	case len(strings.TrimSpace(call.Name)) == 0:
+		return fmt.Errorf("register call: name is empty")
+	case call.Name == HelpCall || strings.HasPrefix(call.Name, "-"):
+		return fmt.Errorf("register call %q: name is reserved", call.Name)
+	case strings.ContainsAny(call.Name, `/`+string(filepath.Separator)) || call.Name == "." || call.Name == "..":
+		return fmt.Errorf("register call %q: name is not a file name", call.Name)
+	case m.Lookup(call.Name) != nil:
+		return fmt.Errorf("register call %q: already registered", call.Name)
+	case call.Run == nil:
+		return fmt.Errorf("register call %q: run is nil", call.Name)
+	case call.Config != nil && !isPtr(call.Config):
+		return fmt.Errorf("register call %q: config [%T] is not a pointer to struct", call.Name, call.Config)
+	}
+	if len(call.Section) == 0 {
+		call.Section = call.Name
+	}
+	m.calls = append(m.calls, &call)
+	return
+}
+
+// Calls lists the registered call names in registration order
+func (m *Multicall) Calls() (names []string) {
+	for _, call := range m.calls {
+		names = append(names, call.Name)
+	}
+	return
+}
+
+// Lookup a registered call by name, nil when not registered
+func (m *Multicall) Lookup(name string) *Call {
+	for _, call := range m.calls {
+		if call.Name == name {
+			return call
+		}
+	}
+	return nil
+}
+
+// program name of the multicall binary
+func (m *Multicall) program() string {
+	if len(m.Name) > 0 {
+		return m.Name
+	}
+	// the executable is the link target when run by a call symlink
+	if path, err := os.Executable(); err == nil {
+		return callName(path)
+	}
+	return pgm
+}
+
+// callName of a program path, the base name without extension
+func callName(path string) string {
+	var base = filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// Usage writes the program usage with the registered calls
+func (m *Multicall) Usage(w io.Writer) {
+	var name = m.program()
+	var width = len(HelpCall)
+	for _, call := range m.calls {
+		width = max(width, len(call.Name))
+	}
+	fmt.Fprintf(w, "Usage: %s call [flags] [args]\n", name)
+	fmt.Fprintf(w, "       call [flags] [args], with call a link to %s\n\nCalls:\n", name)
+	for _, call := range m.calls {
+		fmt.Fprintf(w, "  %-*s  %s\n", width, call.Name, call.Description)
+	}
+	fmt.Fprintf(w, "  %-*s  %s\n", width, HelpCall, "list the calls, or print the flags of a call with help call")
+	fmt.Fprintf(w, "\nRun %s call -h for the flags of a call\n", name)
+	fmt.Fprintf(w, "\nInstall the call links with\n\n  %s --%s DIR [--%s | --%s] [--%s]\n",
+		name, InstallLinksFlag, VerifyLinksFlag, RemoveLinksFlag, DryRunFlag)
+}
+
+/*
+Dispatch runs the call named by the base name of args[0], when run by
+a symlink, else by args[1]. Flags are reset before the call Config is
+configured, so each call sees only its own flags, parsed from the
+arguments after the call name. help, -h and --help print the usage,
+help NAME the flags of the call NAME, --install-links and the other
+link flags, in any order, the call links, see InstallLinks. An
+unknown or missing call, including the NAME of help, prints the
+usage to stderr and returns ErrUnknownCall.
+
+	m.Dispatch(os.Args)
+*/
+func (m *Multicall) Dispatch(args []string) (err error) {
+	defer Trace.ScopedTrace()()
+	if len(args) == 0 {
+		return fmt.Errorf("dispatch: args has no program name")
+	}
+	var program, rest = args[0], args[1:]
+	if call := m.Lookup(callName(program)); call != nil {
+		return m.run(call, program, rest)
+	}
+	if len(rest) == 0 {
+		m.Usage(os.Stderr)
+		return fmt.Errorf("no call given, %w", ErrUnknownCall)
+	}
+	var name = rest[0]
+	if isLinkFlag(name) {
+		return m.installLinks(program, rest)
+	}
+	rest = rest[1:]
+	switch name {
+	case HelpCall, "-h", "-help", "--help":
+		if len(rest) == 0 {
+			m.Usage(PrintOutput)
+			return
+		}
+		if call := m.Lookup(rest[0]); call != nil {
+			return m.run(call, program+" "+call.Name, []string{"--help"})
+		}
+		m.Usage(os.Stderr)
+		return fmt.Errorf("%q %w", rest[0], ErrUnknownCall)
+	}
+	var call = m.Lookup(name)
+	if call == nil {
+		m.Usage(os.Stderr)
+		return fmt.Errorf("%q %w", name, ErrUnknownCall)
+	}
+	return m.run(call, program+" "+name, rest)
+}
+
+// run configures the view of the call with os.Args set to the call
+// arguments and the program name used in search paths, then runs it
+// unless help was requested
+func (m *Multicall) run(call *Call, program string, args []string) (err error) {
+	var priorArgs, priorProgram = os.Args, pgm
+	defer func() { os.Args, pgm, view = priorArgs, priorProgram, nil }()
+	os.Args = append([]string{program}, args...)
+	pgm = m.program()
+	Reset()
+	setHelpText(call.Name + ": " + call.Description)
+	eflag.CommandLine.Usage = usage
+	if view, err = m.view(call); err != nil {
+		return
+	}
+	switch {
+	case view.obj == nil:
+		parseFlags()
+	case m.Configure == nil:
+		err = Configure(view.obj)
+	default:
+		err = m.Configure(view.obj)
+	}
+	view.copyOut()
+	switch {
+	case errors.Is(flagErr, eflag.ErrHelp):
+		// the flag parser printed the usage
+		return
+	case flagErr != nil:
+		return fmt.Errorf("%s %w", call.Name, flagErr)
+	case err != nil:
+		return fmt.Errorf("%s %w", call.Name, err)
+	}
+	return call.Run(eflag.Args())
+}
+
+// callView of a multicall call: a struct of the Shared fields with
+// the call Config under its section, configured in their place
+type callView struct {
+	// Section is the json key of the call section
+	Section string
+	// obj points to the view struct, nil without Shared or Config
+	obj any
+	// shared json names of the top level fields
+	shared map[string]bool
+	// others are the section keys of the other calls
+	others map[string]bool
+	// file is the per call file, empty unless CallFiles
+	file string
+	// flat is the layout of the per call file, the Shared and section
+	// fields top level, nil without Config, flatIndex the index in the
+	// view of each field and own the json names of the section fields
+	flat      reflect.Type
+	flatIndex [][]int
+	own       map[string]bool
+	copyOut   func()
+}
+
+// view of call with the Shared and Config values copied in
+func (m *Multicall) view(call *Call) (v *callView, err error) {
+	v = &callView{Section: call.Section, shared: map[string]bool{}, others: map[string]bool{}, copyOut: func() {}}
+	for _, other := range m.calls {
+		if other.Section != call.Section {
+			v.others[other.Section] = true
+		}
+	}
+	if m.CallFiles {
+		v.file = fmt.Sprintf("${HOME}/.config/%s/%s.json", pgm, call.Name)
+	}
+	var fields []reflect.StructField
+	var index [][]int
+	var names = map[string]bool{}
+	var shared reflect.Value
+	if m.Shared != nil {
+		if !isPtr(m.Shared) {
+			return nil, fmt.Errorf("multicall shared [%T]: object is not a pointer to struct", m.Shared)
+		}
+		shared = reflect.ValueOf(m.Shared).Elem()
+		for _, sf := range structFields(shared.Type()) {
+			if viaPointer(shared.Type(), sf.Index) {
+				continue
+			}
+			var name = sf.Name
+			for names[name] {
+				name += "_"
+			}
+			v.shared[jsonName(sf)], names[name] = true, true
+			index = append(index, sf.Index)
+			fields = append(fields, reflect.StructField{Name: name, Type: sf.Type, Tag: sf.Tag})
+		}
+	}
+	if call.Config != nil {
+		if v.shared[call.Section] {
+			return nil, fmt.Errorf("multicall call %q: a shared field has the json name of section %q", call.Name, call.Section)
+		}
+		var tag = fmt.Sprintf(`json:%q`, call.Section)
+		if len(call.Description) > 0 {
+			tag += fmt.Sprintf(` doc:%q`, call.Description)
+		}
+		var name = "Section"
+		for names[name] {
+			name += "_"
+		}
+		fields = append(fields, reflect.StructField{Name: name, Type: reflect.TypeOf(call.Config).Elem(), Tag: reflect.StructTag(tag)})
+	}
+	if len(fields) == 0 {
+		return
+	}
+	var obj = reflect.New(reflect.StructOf(fields)).Elem()
+	for i, at := range index {
+		obj.Field(i).Set(shared.FieldByIndex(at))
+	}
+	var n = len(index)
+	if call.Config != nil {
+		obj.Field(n).Set(reflect.ValueOf(call.Config).Elem())
+	}
+	v.obj = obj.Addr().Interface()
+	if len(v.file) > 0 && call.Config != nil {
+		v.flatten(fields[:n], reflect.TypeOf(call.Config).Elem(), names)
+	}
+	v.copyOut = func() {
+		for i, at := range index {
+			shared.FieldByIndex(at).Set(obj.Field(i))
+		}
+		if call.Config != nil {
+			reflect.ValueOf(call.Config).Elem().Set(obj.Field(n))
+		}
+	}
+	return
+}
+
+// flatten sets the layout of the per call file, the shared fields then
+// the fields of the section type t not named as a shared field
+func (v *callView) flatten(shared []reflect.StructField, t reflect.Type, names map[string]bool) {
+	var fields = append([]reflect.StructField{}, shared...)
+	v.own = map[string]bool{}
+	for i := range shared {
+		v.flatIndex = append(v.flatIndex, []int{i})
+	}
+	for _, sf := range structFields(t) {
+		if viaPointer(t, sf.Index) || v.shared[jsonName(sf)] {
+			continue
+		}
+		var name = sf.Name
+		for names[name] {
+			name += "_"
+		}
+		v.own[jsonName(sf)], names[name] = true, true
+		v.flatIndex = append(v.flatIndex, append([]int{len(shared)}, sf.Index...))
+		fields = append(fields, reflect.StructField{Name: name, Type: sf.Type, Tag: sf.Tag})
+	}
+	v.flat = reflect.StructOf(fields)
+}
+
+// viaPointer reports a promoted field reached through an embedded
+// pointer, nil until decoded so not copied
+func viaPointer(t reflect.Type, index []int) bool {
+	for _, i := range index[:len(index)-1] {
+		var sf = t.Field(i)
+		if sf.Type.Kind() == reflect.Ptr {
+			return true
+		}
+		t = sf.Type
+	}
+	return false
+}
+
+// view of the multicall call being configured, nil otherwise
+var view *callView
+
+// isCallFile reports the per call file of the call being configured
+func isCallFile(path string) bool {
+	return view != nil && len(view.file) > 0 && ExpandEnvEvalTilde(view.file) == ExpandEnvEvalTilde(path)
+}
+
+// section moves the keys of a per call file that are not shared
+// fields into the section of the call
+func (v *callView) section(tree map[string]any) map[string]any {
+	var out = map[string]any{}
+	var own = map[string]any{}
+	for key, value := range tree {
+		if v.shared[key] || key == "$schema" {
+			out[key] = value
+			continue
+		}
+		own[key] = value
+	}
+	if len(own) > 0 {
+		out[v.Section] = own
+	}
+	return out
+}
+
+// loadCallFile loads the text of the per call file at path into ptr,
+// a view struct, through the flat layout so errors and warnings locate
+// keys in the file as written, recording the section fields under the
+// section
+func (v *callView) loadCallFile(path string, text []byte, ptr any) (err error) {
+	if v.flat == nil {
+		return loadFile(text, ptr, path, interpolates(path))
+	}
+	var obj = reflect.ValueOf(ptr).Elem()
+	var flat = reflect.New(v.flat).Elem()
+	for i, at := range v.flatIndex {
+		flat.Field(i).Set(obj.FieldByIndex(at))
+	}
+	err = loadFile(text, flat.Addr().Interface(), path, interpolates(path))
+	for i, at := range v.flatIndex {
+		obj.FieldByIndex(at).Set(flat.Field(i))
+	}
+	var moved = map[string]Source{}
+	for key, source := range provenance {
+		var top, _, _ = strings.Cut(key, ".")
+		if source.Layer == FileLayer && source.Name == path && v.own[top] {
+			moved[key] = source
+			delete(provenance, key)
+		}
+	}
+	for key, source := range moved {
+		setSource(v.Section+"."+key, source)
+	}
+	return
+}
@@ -0,0 +1,214 @@
+package autocfg
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	eflag "github.com/davidwalter0/go-flag"
+	"github.com/mitchellh/go-homedir"
+)
+
+type loginCall struct {
+	Role string `json:"role"`
+}
+
+type lookupCall struct {
+	Verbose bool `json:"verbose"`
+}
+
+func TestMulticall(t *testing.T) {
+	var args, output = os.Args, PrintOutput
+	defer func() {
+		os.Args, PrintOutput = args, output
+		Reset()
+	}()
+	var login loginCall
+	var lookup lookupCall
+	var ran []string
+	var m = NewMulticall("multicall-test")
+	var record = func(name string) func([]string) error {
+		return func(args []string) error {
+			ran = append(ran, name+" "+strings.Join(args, " "))
+			return nil
+		}
+	}
+	for _, call := range []Call{
+		{Name: "login", Description: "log in with a role", Config: &login, Run: record("login")},
+		{Name: "lookup", Description: "look up the token", Config: &lookup, Run: record("lookup")},
+	} {
+		if err := m.Register(call); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Register(Call{Name: "login", Run: record("login")}); err == nil {
+		t.Errorf("expected a duplicate call error")
+	}
+	if err := m.Register(Call{Name: HelpCall, Run: record("help")}); err == nil {
+		t.Errorf("expected a reserved name error")
+	}
+
+	if err := m.Dispatch([]string{"/usr/bin/multicall-test", "login", "--role", "reader", "extra"}); err != nil {
+		t.Fatal(err)
+	}
+	if login.Role != "reader" || eflag.Lookup("verbose") != nil {
+		t.Errorf("expected role reader and only the login flags got %+v", login)
+	}
+	if err := m.Dispatch([]string{"/usr/local/bin/lookup", "--verbose"}); err != nil {
+		t.Fatal(err)
+	}
+	if !lookup.Verbose || eflag.Lookup("role") != nil {
+		t.Errorf("expected verbose by symlink name and only the lookup flags got %+v", lookup)
+	}
+	if strings.Join(ran, ",") != "login extra,lookup " {
+		t.Errorf("unexpected runs %q", ran)
+	}
+	if os.Args[0] != args[0] || Program() != pgm {
+		t.Errorf("expected os.Args restored got %v", os.Args)
+	}
+
+	var buffer bytes.Buffer
+	PrintOutput = &buffer
+	if err := m.Dispatch([]string{"multicall-test", "help"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, text := range []string{"Usage: multicall-test call", "login   log in with a role", "lookup  look up the token"} {
+		if !strings.Contains(buffer.String(), text) {
+			t.Errorf("expected %q in usage\n%s", text, buffer.String())
+		}
+	}
+	ran = nil
+	if err := m.Dispatch([]string{"multicall-test", "login", "-h"}); err != nil || len(ran) > 0 {
+		t.Errorf("expected help without running login got %v %q", err, ran)
+	}
+	if err := m.Dispatch([]string{"multicall-test", "logout"}); !errors.Is(err, ErrUnknownCall) {
+		t.Errorf("expected ErrUnknownCall got %v", err)
+	}
+	if err := m.Dispatch([]string{"multicall-test", "help", "logout"}); !errors.Is(err, ErrUnknownCall) {
+		t.Errorf("expected ErrUnknownCall for help of an unknown call got %v", err)
+	}
+}
+
+type sharedCall struct {
+	Addr  string `json:"addr"`
+	Debug bool   `json:"debug"`
+}
+
+func TestMulticallSections(t *testing.T) {
+	var args, saved = os.Args, GetMode()
+	homedir.DisableCache = true
+	defer func() {
+		homedir.DisableCache = false
+		os.Args, Strict = args, false
+		_, _ = SetMode(saved)
+		Reset()
+	}()
+	var home = t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("AUTOCFG_FILENAME", "")
+	_, _ = SetMode(Union | Direct)
+	Strict = true
+	var dir = filepath.Join(home, ".config", "sections-test")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	var files = map[string]string{
+		"config.json": `{"addr": "https://shared", "login": {"role": "shared"}, "lookup": {"verbose": true}}`,
+		"login.json":  `{"role": "own", "debug": true}`,
+	}
+	for name, text := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var shared sharedCall
+	var login loginCall
+	var lookup lookupCall
+	var m = NewMulticall("sections-test")
+	m.Shared = &shared
+	m.CallFiles = true
+	var run = func([]string) error { return nil }
+	_ = m.Register(Call{Name: "login", Config: &login, Run: run})
+	_ = m.Register(Call{Name: "lookup", Config: &lookup, Run: run})
+
+	if err := m.Dispatch([]string{"sections-test", "login", "--addr", "https://flag"}); err != nil {
+		t.Fatal(err)
+	}
+	if shared.Addr != "https://flag" || !shared.Debug || login.Role != "own" || lookup.Verbose {
+		t.Errorf("expected the login view with the call file over the section got %+v %+v %+v", shared, login, lookup)
+	}
+	if source := Provenance()["login.role"]; source.Name != filepath.Join(dir, "login.json") {
+		t.Errorf("expected login.role from the call file got %v", source)
+	}
+	shared = sharedCall{}
+	if err := m.Dispatch([]string{"sections-test", "lookup"}); err != nil {
+		t.Fatal(err)
+	}
+	if shared.Addr != "https://shared" || shared.Debug || !lookup.Verbose {
+		t.Errorf("expected the lookup view from the shared file got %+v %+v", shared, lookup)
+	}
+	// errors locate the key in the call file as written
+	if err := os.WriteFile(filepath.Join(dir, "login.json"), []byte("{\n  \"debug\": true,\n  \"role\": 5\n}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var pe *ParseError
+	if err := m.Dispatch([]string{"sections-test", "login"}); !errors.As(err, &pe) || pe.Line != 3 || pe.Column != 11 || pe.Field != "role" {
+		t.Errorf("expected a ParseError at 3:11 for role got %v", err)
+	}
+}
+
+func TestMulticallFlags(t *testing.T) {
+	var args = os.Args
+	defer func() {
+		os.Args = args
+		Reset()
+	}()
+	var login loginCall
+	var ran []string
+	var m = NewMulticall("flags-test")
+	_ = m.Register(Call{Name: "login", Config: &login, Run: func(args []string) error {
+		ran = append(ran, strings.Join(args, " "))
+		return nil
+	}})
+	for line, expect := range map[string]string{
+		"--bogus --role x tail": "error",
+		"--role x -h":           "help",
+		"--role=x --help":       "help",
+		"--role -h tail":        "tail",
+		"--role x tail -h":      "tail -h",
+		"--role x -- -h":        "-h",
+	} {
+		ran = nil
+		var err = m.Dispatch(append([]string{"flags-test", "login"}, strings.Fields(line)...))
+		var got = "help"
+		switch {
+		case err != nil:
+			got = "error"
+		case len(ran) > 0:
+			got = ran[0]
+		}
+		if got != expect {
+			t.Errorf("%q expected %s got %s %v", line, expect, got, err)
+		}
+	}
+}
+
+func TestMulticallSection(t *testing.T) {
+	var m = NewMulticall("section-test")
+	var login loginCall
+	var run = func([]string) error { return nil }
+	_ = m.Register(Call{Name: "token", Section: "vault", Config: &login, Run: run})
+	_ = m.Register(Call{Name: "lookup", Config: &lookupCall{}, Run: run})
+	var v, err = m.view(m.Lookup("token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var field, _ = reflect.TypeOf(v.obj).Elem().FieldByName("Section")
+	if m.Lookup("lookup").Section != "lookup" || field.Tag.Get("json") != "vault" || !v.others["lookup"] || v.others["vault"] {
+		t.Errorf("expected the token call in the vault section got %q %v", field.Tag, v.others)
+	}
+}
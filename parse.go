@@ -0,0 +1,127 @@
+package autocfg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ParseError locates a syntax or type error in a configuration file
+type ParseError struct {
+	// Path of the file
+	Path string
+	// Line and Column of the error, 1 based, 0 when unknown
+	Line, Column int
+	// Excerpt is the source line with a caret under the column
+	Excerpt string
+	// Field is the json key path of the field for type errors
+	Field string
+	// Err from the decoder
+	Err error
+}
+
+// Error formats path:line:column: error followed by the excerpt
+func (e *ParseError) Error() (text string) {
+	text = e.Path
+	if e.Line > 0 {
+		text += fmt.Sprintf(":%d:%d", e.Line, e.Column)
+	}
+	text += ": " + e.Err.Error()
+	if len(e.Field) > 0 {
+		text += fmt.Sprintf(" (field %s)", e.Field)
+	}
+	if len(e.Excerpt) > 0 {
+		text += "\n" + e.Excerpt
+	}
+	return
+}
+
+// Unwrap returns the decoder error
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// parseError wraps a decode error of the json text of the file at
+// path in a ParseError. Syntax errors are located by offset, type
+// errors by the key of the field as decoded text may be rewritten.
+// origin maps json offsets to the source text when it was JSONC.
+func parseError(path string, source, text []byte, origin func(int64) int64, err error) error {
+	var pe = &ParseError{Path: path, Err: err}
+	var offset int64 = -1
+	var syntax *json.SyntaxError
+	var typed *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntax):
+		offset = max(syntax.Offset-1, 0)
+	case errors.As(err, &typed):
+		pe.Field = typed.Field
+		if at, ok := fieldOffset(text, typed.Field); ok {
+			offset = valueOffset(text, at)
+		}
+	}
+	if offset >= 0 {
+		if origin != nil {
+			offset = origin(offset)
+		}
+		pe.Line, pe.Column = position(source, offset)
+		pe.Excerpt = excerpt(source, offset)
+	}
+	return pe
+}
+
+// fieldOffset of the key at the dotted key path, matched case
+// insensitively like encoding/json
+func fieldOffset(text []byte, field string) (offset int64, ok bool) {
+	if len(field) == 0 {
+		return
+	}
+	var offsets = keyOffsets(text)
+	if offset, ok = offsets[field]; ok {
+		return
+	}
+	for key, at := range offsets {
+		if strings.EqualFold(key, field) {
+			return at, true
+		}
+	}
+	return
+}
+
+// valueOffset skips from the key at offset to the start of its value
+func valueOffset(text []byte, offset int64) int64 {
+	var end = bytes.IndexByte(text[offset+1:], '"')
+	if end < 0 {
+		return offset
+	}
+	var i = offset + int64(end) + 2
+	for i < int64(len(text)) && strings.IndexByte(" \t\r\n:", text[i]) >= 0 {
+		i++
+	}
+	return min(i, int64(len(text)))
+}
+
+// excerpt of the line holding offset with a caret under the offset,
+// tabs are kept so the caret aligns
+func excerpt(text []byte, offset int64) string {
+	if offset >= int64(len(text)) {
+		offset = int64(len(text))
+	}
+	var start = bytes.LastIndexByte(text[:offset], '\n') + 1
+	var end = bytes.IndexByte(text[start:], '\n')
+	var line = text[start:]
+	if end >= 0 {
+		line = text[start : start+end]
+	}
+	line = bytes.TrimRight(line, "\r")
+	var caret = []byte{}
+	for _, c := range text[start:offset] {
+		if c == '\t' {
+			caret = append(caret, '\t')
+			continue
+		}
+		caret = append(caret, ' ')
+	}
+	return fmt.Sprintf("\t%s\n\t%s^", line, caret)
+}
@@ -0,0 +1,89 @@
+package autocfg
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type parseConf struct {
+	Server struct {
+		Port int `json:"port"`
+	} `json:"server"`
+}
+
+func TestParseError(t *testing.T) {
+	defer Reset()
+	Reset()
+	var dir = t.TempDir()
+	for _, c := range []struct {
+		text         string
+		line, column int
+		field        string
+		excerpt      string
+	}{
+		{
+			text: "{\n  \"server\": {\n    \"port\": 80,\n  }\n}\n",
+			line: 4, column: 3,
+			excerpt: "\t  }\n\t  ^",
+		},
+		{
+			text: "{\n  \"server\": {\n    \"port\": \"eighty\"\n  }\n}\n",
+			line: 3, column: 13, field: "server.port",
+			excerpt: "\t    \"port\": \"eighty\"\n\t            ^",
+		},
+	} {
+		var file = filepath.Join(dir, "config.json")
+		if err := os.WriteFile(file, []byte(c.text), 0600); err != nil {
+			t.Fatal(err)
+		}
+		var err = LoadDirect(file, &parseConf{})
+		var pe *ParseError
+		if !errors.As(err, &pe) {
+			t.Fatalf("expected a ParseError got %v", err)
+		}
+		if pe.Path != file || pe.Line != c.line || pe.Column != c.column || pe.Field != c.field || pe.Excerpt != c.excerpt {
+			t.Errorf("expected %d:%d %q %q got %d:%d %q %q", c.line, c.column, c.field, c.excerpt, pe.Line, pe.Column, pe.Field, pe.Excerpt)
+		}
+	}
+}
+
+func TestUnionParseError(t *testing.T) {
+	defer func() {
+		Strict = false
+		Reset()
+	}()
+	Reset()
+	var dir = t.TempDir()
+	var bad, good = filepath.Join(dir, "bad.json"), filepath.Join(dir, "good.json")
+	if err := os.WriteFile(bad, []byte(`{"server": {"port": "eighty"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(good, []byte(`{"server": {"port": 8200}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var saved = GetMode()
+	defer func() { _, _ = SetMode(saved) }()
+	_, _ = SetMode(Union | Direct)
+	ResetWarnings()
+	var o parseConf
+	if err := Load(&o, []string{bad, good}, nil); err != nil || o.Server.Port != 8200 {
+		t.Errorf("expected a warning and the good file loaded got %v %+v", err, o)
+	}
+	if len(Warnings()) != 1 || !strings.Contains(Warnings()[0], bad) {
+		t.Errorf("expected a warning naming %s got %q", bad, Warnings())
+	}
+	Strict = true
+	// a plain config in both lists, as AUTOCFG_FILENAME is, loads once
+	_, _ = SetMode(Union | Direct | Indirect)
+	o = parseConf{}
+	if err := Load(&o, []string{good}, []string{good}); err != nil || o.Server.Port != 8200 {
+		t.Errorf("expected the plain config loaded directly got %v %+v", err, o)
+	}
+	var pe *ParseError
+	if err := Load(&parseConf{}, []string{bad, good}, nil); !errors.As(err, &pe) {
+		t.Errorf("expected a ParseError when Strict got %v", err)
+	}
+}
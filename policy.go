@@ -0,0 +1,128 @@
+package autocfg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"sort"
+)
+
+// PolicyPath overrides the /etc/{{pgm}}/policy.json policy location
+var PolicyPath string
+
+// PolicyErrors turns attempts to override policy locked fields into
+// errors, they are warnings by default
+var PolicyErrors bool
+
+// ErrPolicyLocked is wrapped by errors for sources setting a field
+// the policy file locks
+var ErrPolicyLocked = errors.New("locked by policy")
+
+// locked maps the json key path of each policy locked field to the
+// policy file
+var locked = map[string]string{}
+
+// PolicyFile is the path of the enforced policy file
+func PolicyFile() string {
+	if len(PolicyPath) > 0 {
+		return PolicyPath
+	}
+	return fmt.Sprintf("/etc/%s/policy.json", pgm)
+}
+
+// Locked returns the json key paths of the fields locked by the
+// policy file mapped to the policy file path
+func Locked() (fields map[string]string) {
+	defer Trace.ScopedTrace()()
+	fields = make(map[string]string, len(locked))
+	for key, path := range locked {
+		fields[key] = path
+	}
+	return
+}
+
+// ResetPolicy clears the locked fields
+func ResetPolicy() {
+	defer Trace.ScopedTrace()()
+	locked = map[string]string{}
+}
+
+/*
+enforcePolicy loads the policy file over obj after every other source
+and locks each field it sets. A field a file, env var or flag set to
+a different value is reported as a warning naming that source, or an
+ErrPolicyLocked error when PolicyErrors is set; the policy value
+wins either way.
+*/
+func enforcePolicy(obj any) (err error) {
+	defer Trace.ScopedTrace()()
+	var path = ExpandEnvEvalTilde(PolicyFile())
+	var text []byte
+	if text, err = os.ReadFile(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			err = nil
+		}
+		return
+	}
+	var prior = map[string]reflect.Value{}
+	var sources = Provenance()
+	for _, l := range leaves(obj) {
+		prior[l.Key()] = deepCopy(l.Value)
+	}
+	// policy values bypass the sources tags
+	var source = text
+	var origin func(int64) int64
+	if text, origin, err = standardJSON(path, text); err != nil {
+		return
+	}
+	var standard = text
+	var unknown = unknownKeys(text, obj, path)
+	if text, err = prepare(text, obj, path, interpolates(path)); err != nil {
+		return
+	}
+	if err = json.Unmarshal(text, obj); err != nil {
+		return parseError(path, source, standard, origin, err)
+	}
+	var tree = decodeTree(text)
+	var errs = []error{unknown}
+	for _, l := range leaves(obj) {
+		if !hasPath(tree, l.Path) {
+			continue
+		}
+		var key = l.Key()
+		locked[key] = path
+		setSource(key, Source{Layer: PolicyLayer, Name: path, Locked: true})
+		var source, set = sources[key]
+		if !set || source.Layer == DefaultLayer || reflect.DeepEqual(prior[key].Interface(), l.Value.Interface()) {
+			continue
+		}
+		if PolicyErrors {
+			errs = append(errs, fmt.Errorf("%s %w %s, ignoring %s", key, ErrPolicyLocked, path, source))
+			continue
+		}
+		warn("%s is locked by policy %s, ignoring %s", key, path, source)
+	}
+	return errors.Join(errs...)
+}
+
+// policyString describes the policy file and locked fields for String
+func policyString() (text string) {
+	text = fmt.Sprintf("Policy file -- enforced, loaded last, fields it sets are locked\n\t%s\n",
+		ExpandEnvEvalTilde(PolicyFile()))
+	if len(locked) == 0 {
+		return
+	}
+	var keys = make([]string, 0, len(locked))
+	for key := range locked {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	text += "Locked by policy\n"
+	for _, key := range keys {
+		text += fmt.Sprintf("\t%s\n", key)
+	}
+	return
+}
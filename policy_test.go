@@ -0,0 +1,77 @@
+package autocfg
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEnforcePolicy(t *testing.T) {
+	var args = os.Args
+	defer func() {
+		os.Args = args
+		PolicyPath = ""
+		PolicyErrors = false
+		SetPrecedence(nil)
+		Reset()
+	}()
+	PolicyPath = filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(PolicyPath, []byte(`{"layered-role": "policy-role"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetPrecedence(Precedence{SourceDefaults, SourceEnv, SourceFlags}); err != nil {
+		t.Fatal(err)
+	}
+	for _, strict := range []bool{false, true} {
+		Reset()
+		PolicyErrors = strict
+		os.Args = []string{"autocfg.test", "--layered-role=flag-role"}
+		var o = &layeredConf{}
+		var err = Configure(o)
+		if o.LayeredRole != "policy-role" {
+			t.Errorf("policy did not win %+v", o)
+		}
+		if source := Provenance()["layered-role"]; !source.Locked || source.Layer != PolicyLayer {
+			t.Errorf("expected locked policy source got %s", source)
+		}
+		if _, ok := Locked()["layered-role"]; !ok {
+			t.Error("layered-role should be locked")
+		}
+		if !strings.Contains(String(), "layered-role") {
+			t.Error("String should list the locked field")
+		}
+		if strict && !errors.Is(err, ErrPolicyLocked) {
+			t.Errorf("expected ErrPolicyLocked got %v", err)
+		}
+		if !strict && (err != nil || len(Warnings()) != 1) {
+			t.Errorf("expected one warning got %v %v", Warnings(), err)
+		}
+	}
+}
+
+type policyMapConf struct {
+	Labels map[string]string `json:"labels"`
+}
+
+func TestEnforcePolicyMap(t *testing.T) {
+	defer func() {
+		PolicyPath = ""
+		Reset()
+	}()
+	Reset()
+	ResetWarnings()
+	PolicyPath = filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(PolicyPath, []byte(`{"labels": {"team": "policy"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var o = &policyMapConf{Labels: map[string]string{"team": "env"}}
+	setSource("labels", Source{Layer: EnvLayer, Name: "LABELS"})
+	if err := enforcePolicy(o); err != nil || o.Labels["team"] != "policy" {
+		t.Fatalf("expected the policy label got %v %v", err, o.Labels)
+	}
+	if len(Warnings()) != 1 || !strings.Contains(Warnings()[0], "labels is locked") {
+		t.Errorf("expected the overridden map locked with a warning got %q", Warnings())
+	}
+}
@@ -0,0 +1,102 @@
+package autocfg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// position of the byte offset in text as a 1 based line and column
+func position(text []byte, offset int64) (line, column int) {
+	if offset > int64(len(text)) {
+		offset = int64(len(text))
+	}
+	var before = text[:offset]
+	line = bytes.Count(before, []byte("\n")) + 1
+	column = int(offset) - bytes.LastIndexByte(before, '\n')
+	return
+}
+
+// keyLines maps the dotted json key path of each object key in text,
+// array elements by index, to the line the key is on
+func keyLines(text []byte) (lines map[string]int) {
+	lines = map[string]int{}
+	for key, offset := range keyOffsets(text) {
+		lines[key], _ = position(text, offset)
+	}
+	return
+}
+
+// keyOffsets maps the dotted json key path of each object key in text
+// to the offset of the key
+func keyOffsets(text []byte) (offsets map[string]int64) {
+	offsets = map[string]int64{}
+	type frame struct {
+		object bool
+		key    string
+		index  int
+		isKey  bool
+	}
+	var stack []*frame
+	var at = func() (path []string) {
+		for _, f := range stack[:len(stack)-1] {
+			if f.object {
+				path = append(path, f.key)
+				continue
+			}
+			path = append(path, strconv.Itoa(f.index))
+		}
+		return
+	}
+	// done marks the value of the enclosing object key or array
+	// element complete
+	var done = func() {
+		if len(stack) == 0 {
+			return
+		}
+		if top := stack[len(stack)-1]; top.object {
+			top.isKey = true
+		} else {
+			top.index++
+		}
+	}
+	var decoder = json.NewDecoder(bytes.NewReader(text))
+	decoder.UseNumber()
+	for {
+		var token, err = decoder.Token()
+		if err != nil {
+			return
+		}
+		switch token {
+		case json.Delim('{'):
+			stack = append(stack, &frame{object: true, isKey: true})
+			continue
+		case json.Delim('['):
+			stack = append(stack, &frame{})
+			continue
+		case json.Delim('}'), json.Delim(']'):
+			stack = stack[:len(stack)-1]
+			done()
+			continue
+		}
+		if len(stack) > 0 {
+			if top := stack[len(stack)-1]; top.object && top.isKey {
+				top.key, top.isKey = fmt.Sprint(token), false
+				var end = decoder.InputOffset()
+				offsets[strings.Join(append(at(), top.key), ".")] = int64(bytes.LastIndexByte(text[:end-1], '"'))
+				continue
+			}
+		}
+		done()
+	}
+}
+
+// location of the key path in the file at path, path:line when found
+func location(path string, lines map[string]int, key []string) string {
+	if line, ok := lines[strings.Join(key, ".")]; ok {
+		return fmt.Sprintf("%s:%d", path, line)
+	}
+	return path
+}
@@ -0,0 +1,421 @@
+package autocfg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// Source names for a Precedence list
+const (
+	// SourceDefaults default struct tag values
+	SourceDefaults = "defaults"
+	// SourceEtc /etc/{{pgm}}/config.json and autocfg.json
+	SourceEtc = "etc"
+	// SourceXDG ${XDG_CONFIG_HOME:-~/.config}/{{pgm}}/config.json and
+	// autocfg.json
+	SourceXDG = "xdg"
+	// SourceHome ~/.{{pgm}}.json
+	SourceHome = "home"
+	// SourceLocal .{{pgm}}.json, .config.json in simple mode, and
+	// .autocfg.json in the current directory
+	SourceLocal = "local"
+	// SourceFilename the file named by AUTOCFG_FILENAME
+	SourceFilename = "AUTOCFG_FILENAME"
+	// SourceEnv environment variables, including dotenv definitions
+	SourceEnv = "env"
+	// SourceFlags command line flags
+	SourceFlags = "flags"
+	// SourcePolicy /etc/{{pgm}}/policy.json, the enforced layer
+	SourcePolicy = "policy"
+)
+
+// Precedence lists source names from lowest to highest priority, each
+// source replaces the values set by the sources before it. The
+// policy source is enforced: it is always applied last, whether or
+// not and wherever it is listed, so no other source can override it.
+type Precedence []string
+
+// DefaultPrecedence is a complete ordering of the sources
+var DefaultPrecedence = Precedence{
+	SourceDefaults,
+	SourceEtc,
+	SourceXDG,
+	SourceHome,
+	SourceLocal,
+	SourceFilename,
+	SourceEnv,
+	SourceFlags,
+	SourcePolicy,
+}
+
+// precedence when set replaces the fixed file, env, flag pipeline
+var precedence Precedence
+
+var sourceNames = map[string]bool{
+	SourceDefaults: true, SourceEtc: true, SourceXDG: true, SourceHome: true,
+	SourceLocal: true, SourceFilename: true, SourceEnv: true, SourceFlags: true,
+	SourcePolicy: true,
+}
+
+// SetPrecedence orders the sources used by Configure and the
+// multicall variants. A nil list restores the fixed file, env, flag
+// order of the search modes. The order is the default of every call,
+// a Loader with its own Precedence replaces it for its calls.
+func SetPrecedence(p Precedence) (err error) {
+	defer Trace.ScopedTrace()()
+	if p == nil {
+		precedence = nil
+		return
+	}
+	precedence, err = ordered(p)
+	return
+}
+
+// ordered checks the source names of p and moves policy last
+func ordered(p Precedence) (list Precedence, err error) {
+	var seen = map[string]bool{}
+	for _, name := range p {
+		if !sourceNames[name] {
+			return nil, fmt.Errorf("unknown precedence source %q", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate precedence source %q", name)
+		}
+		seen[name] = true
+		if name == SourcePolicy {
+			continue
+		}
+		list = append(list, name)
+	}
+	return append(list, SourcePolicy), nil
+}
+
+// Loader configures objects with its own source order
+type Loader struct {
+	// Precedence of the sources of the loader calls, nil uses the
+	// order set by SetPrecedence
+	Precedence Precedence
+}
+
+// Configure obj as Configure does in the loader precedence
+func (loader Loader) Configure(obj any) (err error) {
+	defer Trace.ScopedTrace()()
+	return loader.with(func() error { return Configure(obj) })
+}
+
+// MultiCallConfigure obj as MultiCallConfigure does in the loader
+// precedence
+func (loader Loader) MultiCallConfigure(obj any) (err error) {
+	defer Trace.ScopedTrace()()
+	return loader.with(func() error { return MultiCallConfigure(obj) })
+}
+
+// UnprefixedMultiCallConfigure obj as UnprefixedMultiCallConfigure
+// does in the loader precedence
+func (loader Loader) UnprefixedMultiCallConfigure(obj any) (err error) {
+	defer Trace.ScopedTrace()()
+	return loader.with(func() error { return UnprefixedMultiCallConfigure(obj) })
+}
+
+// PrefixMultiCallConfigure obj as PrefixMultiCallConfigure does in the
+// loader precedence
+func (loader Loader) PrefixMultiCallConfigure(prefix string, obj any) (err error) {
+	defer Trace.ScopedTrace()()
+	return loader.with(func() error { return PrefixMultiCallConfigure(prefix, obj) })
+}
+
+// with runs configure in the loader precedence, restoring the default
+// order after
+func (loader Loader) with(configure func() error) (err error) {
+	if loader.Precedence == nil {
+		return configure()
+	}
+	var list Precedence
+	if list, err = ordered(loader.Precedence); err != nil {
+		return
+	}
+	var prior = precedence
+	precedence = list
+	defer func() { precedence = prior }()
+	return configure()
+}
+
+// GetPrecedence returns the source order, nil when unset
+func GetPrecedence() Precedence {
+	defer Trace.ScopedTrace()()
+	return append(Precedence(nil), precedence...)
+}
+
+// xdgConfigHome ${XDG_CONFIG_HOME} or ${HOME}/.config
+func xdgConfigHome() string {
+	if dir := Getenv("XDG_CONFIG_HOME"); len(dir) > 0 {
+		return dir
+	}
+	return "${HOME}/.config"
+}
+
+// localFileName .{{pgm}}.json or the simple mode file name
+func localFileName() string {
+	if mode&Simple == Simple {
+		return LocalConfigFileName
+	}
+	return fmt.Sprintf(".%s.json", pgm)
+}
+
+// SourceFiles returns the direct configuration and indirect autocfg
+// paths of a file source
+func SourceFiles(name string) (direct, indirect []string) {
+	defer Trace.ScopedTrace()()
+	switch name {
+	case SourceEtc:
+		direct = []string{fmt.Sprintf("/etc/%s/config.json", pgm)}
+		indirect = []string{fmt.Sprintf("/etc/%s/autocfg.json", pgm)}
+	case SourceXDG:
+		var dir = filepath.Join(xdgConfigHome(), pgm)
+		direct = []string{filepath.Join(dir, "config.json")}
+		indirect = []string{filepath.Join(dir, "autocfg.json")}
+	case SourceHome:
+		direct = []string{fmt.Sprintf("${HOME}/.%s.json", pgm)}
+	case SourceLocal:
+		direct = []string{localFileName()}
+		indirect = []string{LocalAutoConfigFileName}
+	case SourceFilename:
+		if ePath := Getenv("AUTOCFG_FILENAME"); len(ePath) > 0 {
+			direct = []string{ePath}
+			indirect = []string{ePath}
+		}
+	case SourcePolicy:
+		direct = []string{PolicyFile()}
+	}
+	return
+}
+
+// precedenceFiles lists the direct and indirect paths of the file
+// sources in precedence order, lowest priority first
+func precedenceFiles() (direct, indirect []string) {
+	for _, name := range precedence {
+		var d, i = SourceFiles(name)
+		direct = append(direct, d...)
+		indirect = append(indirect, i...)
+	}
+	return
+}
+
+// isAutoCfg reports whether the json file at path is an autocfg file,
+// an object with a path attribute
+func isAutoCfg(path string) bool {
+	var text, err = os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	var autoCfg = &AutoCfg{}
+	if text, _, err = standardJSON(path, text); err != nil {
+		return false
+	}
+	return json.Unmarshal(text, autoCfg) == nil && len(autoCfg.Path) > 0
+}
+
+// loadSource loads the files of a file source into obj. Direct files
+// load when mode includes Direct, indirect autocfg files when it
+// includes Indirect; AUTOCFG_FILENAME is loaded as whichever kind it
+// is. found reports whether any file existed.
+func loadSource(name string, obj any) (found bool, err error) {
+	var direct, indirect = SourceFiles(name)
+	if name == SourceFilename && len(direct) > 0 {
+		var path = ExpandEnvEvalTilde(direct[0])
+		if _, err = os.Stat(path); err != nil {
+			return false, nil
+		}
+		if isAutoCfg(path) {
+			return true, LoadIndirect(path, obj)
+		}
+		return true, LoadDirect(path, obj)
+	}
+	if mode&Indirect == Indirect {
+		for _, path := range indirect {
+			path = ExpandEnvEvalTilde(path)
+			if _, serr := os.Stat(path); serr != nil {
+				continue
+			}
+			found = true
+			if err = LoadIndirect(path, obj); err != nil {
+				return
+			}
+		}
+	}
+	if mode&Direct == Direct {
+		for _, path := range direct {
+			path = ExpandEnvEvalTilde(path)
+			if _, serr := os.Stat(path); serr != nil {
+				continue
+			}
+			found = true
+			if err = LoadDirect(path, obj); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// snapshot copies the values of the leaves of obj whose source is in
+// layers, keyed by json key path
+func snapshot(obj any, sources map[string]Source, layers ...Layer) (values map[string]reflect.Value) {
+	values = map[string]reflect.Value{}
+	for _, l := range leaves(obj) {
+		var source, ok = sources[l.Key()]
+		if !ok {
+			continue
+		}
+		for _, layer := range layers {
+			if source.Layer == layer {
+				var copied = reflect.New(l.Value.Type()).Elem()
+				copied.Set(l.Value)
+				values[l.Key()] = copied
+			}
+		}
+	}
+	return
+}
+
+// restore sets the leaves of obj from values, recording sources, and
+// rejecting values from sources the field excludes
+func restore(obj any, values map[string]reflect.Value, sources map[string]Source) (err error) {
+	var errs []error
+	for _, l := range leaves(obj) {
+		var value, ok = values[l.Key()]
+		if !ok {
+			continue
+		}
+		if source := sources[l.Key()]; !allowed(l.Field, source.Layer) {
+			errs = append(errs, notAllowed(l, source))
+			continue
+		}
+		l.Value.Set(value)
+		setSource(l.Key(), sources[l.Key()])
+	}
+	return errors.Join(errs...)
+}
+
+// applyDefaults sets each field with a default tag from the tag text
+func applyDefaults(obj any) (err error) {
+	var errs []error
+	for _, l := range leaves(obj) {
+		var text = l.Field.Tag.Get("default")
+		if len(text) == 0 {
+			continue
+		}
+		if derr := setValue(l.Value, text); derr != nil {
+			errs = append(errs, fmt.Errorf("default %s: %w", l.Key(), derr))
+			continue
+		}
+		setSource(l.Key(), Source{Layer: DefaultLayer})
+	}
+	return errors.Join(errs...)
+}
+
+// layered configures obj applying the sources in precedence order.
+// define registers the flags of its argument with go-cfg, it is
+// called with a scratch copy of obj so the default, env and flag
+// values can be applied at their place in the order.
+func layered(obj any, define func(any) error) (err error) {
+	defer Trace.ScopedTrace()()
+	if !isPtr(obj) {
+		return fmt.Errorf("arg obj any [%T]: object is not a pointer to struct", obj)
+	}
+	if err = scanStandardFlags(obj); err != nil {
+		return
+	}
+	if Dotenv {
+		if err = LoadDotenvFiles(); err != nil {
+			return
+		}
+	}
+	var scratch = reflect.New(reflect.TypeOf(obj).Elem()).Interface()
+	ResetProvenance()
+	ResetPolicy()
+	if err = define(scratch); err != nil {
+		return
+	}
+	defineAliases(scratch)
+	defineCheckFlag()
+	defineStandardFlags()
+	defineCompletionFlag()
+	hideRestricted(scratch)
+	recordDefined(scratch)
+	if err = envLayer(scratch); err != nil {
+		return
+	}
+	if err = aliasEnv(scratch); err != nil {
+		return
+	}
+	var envValues = snapshot(scratch, provenance, EnvLayer, DotenvLayer)
+	parseFlags()
+	runCheckFlag(obj)
+	runCompletionFlag(scratch)
+	recordFlags(scratch)
+	warnDeprecated(scratch)
+	var flagValues = snapshot(scratch, provenance, FlagLayer)
+	var sources = Provenance()
+	ResetProvenance()
+
+	var files = precedenceFileSources()
+	for _, name := range precedence {
+		switch name {
+		case SourceDefaults:
+			err = applyDefaults(obj)
+		case SourceEnv:
+			err = restore(obj, envValues, sources)
+		case SourceFlags:
+			err = restore(obj, flagValues, sources)
+		case SourcePolicy:
+			err = enforcePolicy(obj)
+		default:
+			if files[name] {
+				_, err = loadSource(name, obj)
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+	if err = ResolveReferences(obj); err != nil {
+		return
+	}
+	runStandardFlags(obj)
+	if Debug() {
+		fmt.Fprintf(os.Stderr, "\nafter layered configure %s\n", strings.Join(precedence, ", "))
+		Dump(obj)
+	}
+	return
+}
+
+// precedenceFileSources selects the file sources to load. Union mode
+// loads all of them, First mode only the highest priority source with
+// a file present.
+func precedenceFileSources() (load map[string]bool) {
+	load = map[string]bool{}
+	for i := len(precedence) - 1; i >= 0; i-- {
+		var name = precedence[i]
+		if name == SourceDefaults || name == SourceEnv || name == SourceFlags || name == SourcePolicy {
+			continue
+		}
+		if mode&First != First {
+			load[name] = true
+			continue
+		}
+		var direct, indirect = SourceFiles(name)
+		for _, path := range append(direct, indirect...) {
+			if _, err := os.Stat(ExpandEnvEvalTilde(path)); err == nil {
+				load[name] = true
+				return
+			}
+		}
+	}
+	return
+}
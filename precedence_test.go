@@ -0,0 +1,101 @@
+package autocfg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type layeredConf struct {
+	LayeredRole   string `json:"layered-role" default:"default-role"`
+	LayeredSecret string `json:"layered-secret"`
+	LayeredMount  string `json:"layered-mount" default:"approle"`
+}
+
+func TestSetPrecedence(t *testing.T) {
+	defer SetPrecedence(nil)
+	if err := SetPrecedence(Precedence{SourceEnv, "bogus"}); err == nil {
+		t.Error("expected unknown source error")
+	}
+	if err := SetPrecedence(Precedence{SourcePolicy, SourceEnv, SourceFlags}); err != nil {
+		t.Fatal(err)
+	}
+	if p := GetPrecedence(); p[len(p)-1] != SourcePolicy {
+		t.Errorf("policy should be enforced last %v", p)
+	}
+}
+
+func TestLayeredConfigure(t *testing.T) {
+	var args = os.Args
+	var saved = mode
+	defer func() {
+		os.Args = args
+		mode = saved
+		SetPrecedence(nil)
+		Reset()
+	}()
+	Reset()
+	var file = filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(file, []byte(`{"layered-role": "file-role", "layered-secret": "file-secret"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AUTOCFG_FILENAME", file)
+	t.Setenv("LAYERED_ROLE", "env-role")
+	t.Setenv("LAYERED_SECRET", "env-secret")
+	os.Args = []string{"autocfg.test", "--layered-secret=flag-secret"}
+	SetMode(Union | Direct)
+	if err := SetPrecedence(Precedence{SourceDefaults, SourceEnv, SourceFilename, SourceFlags}); err != nil {
+		t.Fatal(err)
+	}
+	var o = &layeredConf{}
+	if err := Configure(o); err != nil {
+		t.Fatal(err)
+	}
+	if o.LayeredRole != "file-role" || o.LayeredSecret != "flag-secret" || o.LayeredMount != "approle" {
+		t.Errorf("unexpected layering %+v", o)
+	}
+	var sources = Provenance()
+	if sources["layered-role"].Layer != FileLayer || sources["layered-secret"].Layer != FlagLayer ||
+		sources["layered-mount"].Layer != DefaultLayer {
+		t.Errorf("unexpected provenance %v", sources)
+	}
+}
+
+func TestLoaderPrecedence(t *testing.T) {
+	var args = os.Args
+	var saved = mode
+	defer func() {
+		os.Args = args
+		mode = saved
+		SetPrecedence(nil)
+		Reset()
+	}()
+	Reset()
+	var file = filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(file, []byte(`{"layered-role": "file-role"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AUTOCFG_FILENAME", file)
+	t.Setenv("LAYERED_ROLE", "env-role")
+	os.Args = []string{"autocfg.test"}
+	SetMode(Union | Direct)
+	if err := SetPrecedence(Precedence{SourceEnv, SourceFilename}); err != nil {
+		t.Fatal(err)
+	}
+	var loader = Loader{Precedence: Precedence{SourceFilename, SourceEnv}}
+	var o = &layeredConf{}
+	if err := loader.Configure(o); err != nil || o.LayeredRole != "env-role" {
+		t.Errorf("expected the loader order to let env win got %v %+v", err, o)
+	}
+	if p := GetPrecedence(); len(p) != 3 || p[0] != SourceEnv {
+		t.Errorf("expected the default order kept got %v", p)
+	}
+	Reset()
+	o = &layeredConf{}
+	if err := Configure(o); err != nil || o.LayeredRole != "file-role" {
+		t.Errorf("expected the default order to let the file win got %v %+v", err, o)
+	}
+	if err := (Loader{Precedence: Precedence{"bogus"}}).Configure(&layeredConf{}); err == nil {
+		t.Error("expected unknown source error")
+	}
+}
@@ -0,0 +1,190 @@
+package autocfg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	eflag "github.com/davidwalter0/go-flag"
+)
+
+// Layer names the kind of source a field value came from
+type Layer string
+
+const (
+	// DefaultLayer values come from default struct tags
+	DefaultLayer Layer = "default"
+	// FileLayer values come from a configuration file
+	FileLayer Layer = "file"
+	// DotenvLayer values come from a variable defined in a dotenv file
+	DotenvLayer Layer = "dotenv"
+	// EnvLayer values come from the environment
+	EnvLayer Layer = "env"
+	// FlagLayer values come from the command line
+	FlagLayer Layer = "flag"
+	// PolicyLayer values come from the enforced policy file
+	PolicyLayer Layer = "policy"
+)
+
+// Source of a field value, Name is the file path, env var or flag
+type Source struct {
+	Layer  Layer  `json:"layer"`
+	Name   string `json:"name,omitempty"`
+	Locked bool   `json:"locked,omitempty"`
+}
+
+// String formats the source as "layer name", marking locked fields
+func (source Source) String() (text string) {
+	text = string(source.Layer)
+	if len(source.Name) > 0 {
+		text += " " + source.Name
+	}
+	if source.Locked {
+		text += " (locked)"
+	}
+	return
+}
+
+// provenance maps json key paths to the source of the current value
+var provenance = map[string]Source{}
+
+// Provenance returns a copy of the source of each field set during
+// the last configure, keyed by json key path
+func Provenance() (sources map[string]Source) {
+	defer Trace.ScopedTrace()()
+	sources = make(map[string]Source, len(provenance))
+	for key, source := range provenance {
+		sources[key] = source
+	}
+	return
+}
+
+// ProvenanceString lists the source of each field one per line
+func ProvenanceString() (text string) {
+	defer Trace.ScopedTrace()()
+	var keys = make([]string, 0, len(provenance))
+	for key := range provenance {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		text += fmt.Sprintf("\t%-32s %s\n", key, provenance[key])
+	}
+	return
+}
+
+// ResetProvenance clears the recorded sources
+func ResetProvenance() {
+	defer Trace.ScopedTrace()()
+	provenance = map[string]Source{}
+}
+
+func setSource(key string, source Source) {
+	provenance[key] = source
+}
+
+// recordFile marks the fields of obj present as keys in the json text
+// of the file at path
+func recordFile(text []byte, obj any, path string) {
+	var tree = decodeTree(text)
+	for _, l := range leaves(obj) {
+		if hasPath(tree, l.Path) {
+			setSource(l.Key(), Source{Layer: FileLayer, Name: path})
+		}
+	}
+}
+
+// decodeTree decodes json text to maps, slices and json.Number, nil
+// when the text is invalid
+func decodeTree(text []byte) (tree any) {
+	var decoder = json.NewDecoder(bytes.NewReader(text))
+	decoder.UseNumber()
+	if decoder.Decode(&tree) != nil {
+		return nil
+	}
+	return
+}
+
+// hasPath reports whether a decoded json tree holds the key path,
+// matching keys case insensitively like encoding/json
+func hasPath(tree any, path []string) bool {
+	for _, key := range path {
+		var object, ok = tree.(map[string]any)
+		if !ok {
+			return false
+		}
+		if tree, ok = object[key]; ok {
+			continue
+		}
+		var found bool
+		for k, v := range object {
+			if strings.EqualFold(k, key) {
+				tree, found = v, true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// envSource for a variable, distinguishing dotenv definitions
+func envSource(name string) Source {
+	if file, ok := dotenvSource[name]; ok {
+		return Source{Layer: DotenvLayer, Name: name + " " + file}
+	}
+	return Source{Layer: EnvLayer, Name: name}
+}
+
+// recordDefined marks the fields of obj set when go-cfg defined their
+// flags, from default tags and the go-cfg env layer
+func recordDefined(obj any) {
+	var flags = flagsByAddr()
+	for _, l := range leaves(obj) {
+		var flag, ok = flags[l.Addr()]
+		if !ok {
+			continue
+		}
+		if len(l.Field.Tag.Get("default")) > 0 {
+			setSource(l.Key(), Source{Layer: DefaultLayer})
+		}
+		if envPolicy != nil {
+			continue
+		}
+		var name = flagEnvName(flag)
+		if value, set := LookupEnv(name); set && len(value) > 0 {
+			setSource(l.Key(), envSource(name))
+		}
+	}
+}
+
+// recordFlags marks the fields of obj set on the command line
+func recordFlags(obj any) {
+	var flags = flagsByAddr()
+	var actual = map[string]bool{}
+	eflag.Visit(func(flag *eflag.Flag) {
+		if alias, ok := aliasFlags[flag.Name]; ok {
+			actual[alias.Name] = true
+			warn("flag --%s is deprecated, %s", flag.Name, alias.Message)
+			return
+		}
+		actual[flag.Name] = true
+	})
+	for _, l := range leaves(obj) {
+		if flag, ok := flags[l.Addr()]; ok && actual[flag.Name] {
+			setSource(l.Key(), Source{Layer: FlagLayer, Name: "--" + flag.Name})
+		}
+	}
+}
+
+// flagEnvName extracts the env var name go-cfg writes in flag usage
+func flagEnvName(flag *eflag.Flag) (name string) {
+	if label := envLabel.FindString(flag.Usage); len(label) > 0 {
+		name = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(label, "Env "), ":"))
+	}
+	return
+}
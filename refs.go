@@ -0,0 +1,221 @@
+package autocfg
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ErrUnresolvedReference is wrapped by errors for a ${path} naming
+// no field of the configuration
+var ErrUnresolvedReference = errors.New("unresolved reference")
+
+// ErrReferenceCycle is wrapped by errors for fields referencing
+// themselves directly or through other fields
+var ErrReferenceCycle = errors.New("reference cycle")
+
+// isReference reports whether the body of a ${...} expression names a
+// configuration field rather than an env var: a json key path with a
+// leading dot, ${.data-dir}, or with a dot after a plain name,
+// ${approle.mount}
+func isReference(body string) bool {
+	if len(body) < 2 {
+		return false
+	}
+	for i := 0; i < len(body); i++ {
+		if c := body[i]; !isNameByte(c, false) && c != '-' && c != '.' {
+			return false
+		}
+	}
+	if body[0] == '.' {
+		return true
+	}
+	head, _, found := strings.Cut(body, ".")
+	return found && len(head) > 0 && !strings.Contains(head, "-")
+}
+
+/*
+ResolveReferences replaces ${path} references in the string fields
+of obj with the value of the field at path, where path is the json
+key path from the root of obj, e.g. "${.data-dir}/logs" or
+"auth/${approle.mount}/login". A referenced string field is resolved
+first, so references chain. Configure and the multicall variants
+call ResolveReferences after files, env and flags are applied.
+
+Unknown paths fail with ErrUnresolvedReference and self referencing
+chains with ErrReferenceCycle, both naming the field paths involved.
+A reference is kept literally when written $${path}.
+*/
+func ResolveReferences(obj any) (err error) {
+	defer Trace.ScopedTrace()()
+	if !isPtr(obj) {
+		return fmt.Errorf("arg obj any [%T]: object is not a pointer to struct", obj)
+	}
+	var r = &resolver{
+		root:   reflect.ValueOf(obj).Elem(),
+		done:   map[string]bool{},
+		active: map[string]bool{},
+	}
+	return r.walk(r.root, "")
+}
+
+type resolver struct {
+	root   reflect.Value
+	done   map[string]bool
+	active map[string]bool
+	stack  []string
+}
+
+// walk each string in v, a value at json path at
+func (r *resolver) walk(v reflect.Value, at string) (err error) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			err = r.walk(v.Elem(), at)
+		}
+	case reflect.Struct:
+		for _, sf := range structFields(v.Type()) {
+			var fv, ferr = v.FieldByIndexErr(sf.Index)
+			if ferr != nil {
+				continue
+			}
+			if err = r.walk(fv, join(at, jsonName(sf))); err != nil {
+				return
+			}
+		}
+	case reflect.String:
+		err = r.resolve(v, at)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err = r.walk(v.Index(i), at+"["+strconv.Itoa(i)+"]"); err != nil {
+				return
+			}
+		}
+	case reflect.Map:
+		if v.Type().Elem().Kind() != reflect.String {
+			return
+		}
+		for _, key := range v.MapKeys() {
+			var text string
+			if text, err = r.expand(v.MapIndex(key).String(), fmt.Sprintf("%s[%v]", at, key)); err != nil {
+				return
+			}
+			v.SetMapIndex(key, reflect.ValueOf(text).Convert(v.Type().Elem()))
+		}
+	}
+	return
+}
+
+// resolve the references of the string field v at path, once
+func (r *resolver) resolve(v reflect.Value, path string) (err error) {
+	if r.done[path] {
+		return
+	}
+	if r.active[path] {
+		return fmt.Errorf("%w %s -> %s", ErrReferenceCycle, strings.Join(r.stack, " -> "), path)
+	}
+	r.active[path] = true
+	r.stack = append(r.stack, path)
+	var text string
+	if text, err = r.expand(v.String(), path); err == nil && v.CanSet() {
+		v.SetString(text)
+	}
+	r.stack = r.stack[:len(r.stack)-1]
+	delete(r.active, path)
+	r.done[path] = err == nil
+	return
+}
+
+// expand the references in text, the value of the field at path
+func (r *resolver) expand(text, at string) (string, error) {
+	if !strings.Contains(text, "${") {
+		return text, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		var escaped = strings.HasPrefix(text[i:], "$${")
+		if !escaped && !strings.HasPrefix(text[i:], "${") {
+			b.WriteByte(text[i])
+			continue
+		}
+		var open = i + 2
+		if escaped {
+			open++
+		}
+		var end = closingBrace(text, open)
+		if end < 0 || !isReference(text[open:end]) {
+			b.WriteByte(text[i])
+			continue
+		}
+		var body = text[open:end]
+		i = end
+		if escaped {
+			b.WriteString("${" + body + "}")
+			continue
+		}
+		var target, path, ok = r.lookup(body)
+		if !ok {
+			return "", fmt.Errorf("%w ${%s} in %s", ErrUnresolvedReference, body, at)
+		}
+		if target.Kind() == reflect.String {
+			if err := r.resolve(target, path); err != nil {
+				return "", err
+			}
+			b.WriteString(target.String())
+			continue
+		}
+		if !target.CanInterface() {
+			return "", fmt.Errorf("%w ${%s} in %s", ErrUnresolvedReference, body, at)
+		}
+		b.WriteString(fmt.Sprint(target.Interface()))
+	}
+	return b.String(), nil
+}
+
+// lookup the value at a dot separated json key path returning the
+// canonical path of the field found
+func (r *resolver) lookup(ref string) (v reflect.Value, path string, ok bool) {
+	v = r.root
+	for _, key := range strings.Split(strings.TrimPrefix(ref, "."), ".") {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return v, path, false
+			}
+			v = v.Elem()
+		}
+		switch v.Kind() {
+		case reflect.Struct:
+			var sf, found = lookupField(v.Type(), key)
+			if !found {
+				return v, path, false
+			}
+			var err error
+			if v, err = v.FieldByIndexErr(sf.Index); err != nil {
+				return v, path, false
+			}
+			path = join(path, jsonName(sf))
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return v, path, false
+			}
+			v = v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key()))
+			if !v.IsValid() {
+				return v, path, false
+			}
+			path = fmt.Sprintf("%s[%s]", path, key)
+		default:
+			return v, path, false
+		}
+	}
+	return v, path, len(path) > 0
+}
+
+// join json key path parts
+func join(at, key string) string {
+	if len(at) == 0 {
+		return key
+	}
+	return at + "." + key
+}
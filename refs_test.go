@@ -0,0 +1,45 @@
+package autocfg
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestResolveReferences(t *testing.T) {
+	type login struct {
+		Mount string `json:"mount"`
+		Login string `json:"login"`
+	}
+	type conf struct {
+		DataDir string `json:"data-dir"`
+		LogDir  string `json:"log-dir"`
+		Port    int    `json:"port"`
+		Addr    string `json:"addr"`
+		Escaped string `json:"escaped"`
+		Approle login  `json:"approle"`
+	}
+	var o = &conf{
+		DataDir: "/var/lib/app",
+		LogDir:  "${.data-dir}/logs",
+		Port:    8200,
+		Addr:    "localhost:${.port}",
+		Escaped: "$${.data-dir}",
+		Approle: login{Mount: "approle_org", Login: "auth/${approle.mount}/login"},
+	}
+	if err := ResolveReferences(o); err != nil {
+		t.Fatal(err)
+	}
+	if o.LogDir != "/var/lib/app/logs" || o.Addr != "localhost:8200" ||
+		o.Escaped != "${.data-dir}" || o.Approle.Login != "auth/approle_org/login" {
+		t.Errorf("unexpected resolution %+v", o)
+	}
+
+	o = &conf{LogDir: "${.missing}"}
+	if err := ResolveReferences(o); !errors.Is(err, ErrUnresolvedReference) {
+		t.Errorf("expected ErrUnresolvedReference got %v", err)
+	}
+	o = &conf{DataDir: "${.log-dir}", LogDir: "${.data-dir}"}
+	if err := ResolveReferences(o); !errors.Is(err, ErrReferenceCycle) {
+		t.Errorf("expected ErrReferenceCycle got %v", err)
+	}
+}
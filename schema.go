@@ -0,0 +1,200 @@
+package autocfg
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// SchemaDraft is the JSON Schema dialect Schema generates
+const SchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// SchemaRef when set is written as the "$schema" key of generated
+// JSON templates so editors validate and complete the file
+var SchemaRef string
+
+// Schema returns a JSON Schema describing the configuration files of
+// obj: json names are properties, doc tags descriptions, default tags
+// defaults, the validation tags constraints, and aliases deprecated
+// properties. Unknown keys are not allowed. A struct type containing
+// itself refers to its schema, "#" for obj and "$defs" for others.
+func Schema(obj any) (text []byte, err error) {
+	defer Trace.ScopedTrace()()
+	if !isPtr(obj) {
+		return nil, fmt.Errorf("arg obj any [%T]: object is not a pointer to struct", obj)
+	}
+	var root = elemType(reflect.TypeOf(obj))
+	var w = &schemaWalk{root: root, active: map[reflect.Type]bool{},
+		cycles: map[reflect.Type]bool{}, defs: map[string]any{}}
+	var schema map[string]any
+	if schema, err = w.typeSchema(root); err != nil {
+		return
+	}
+	if len(w.defs) > 0 {
+		schema["$defs"] = w.defs
+	}
+	schema["$schema"] = SchemaDraft
+	schema["title"] = pgm
+	// a "$schema" reference in the file itself is allowed
+	schema["properties"].(map[string]any)["$schema"] = map[string]any{"type": "string"}
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// schemaWalk tracks the struct types being described to refer to a
+// type inside itself rather than recurse
+type schemaWalk struct {
+	root   reflect.Type
+	active map[reflect.Type]bool
+	cycles map[reflect.Type]bool
+	defs   map[string]any
+}
+
+// ref to the schema of t, the document root or its $defs entry
+func (w *schemaWalk) ref(t reflect.Type) map[string]any {
+	if t == w.root {
+		return map[string]any{"$ref": "#"}
+	}
+	return map[string]any{"$ref": "#/$defs/" + t.String()}
+}
+
+// typeSchema of a Go type as decoded by encoding/json
+func (w *schemaWalk) typeSchema(t reflect.Type) (schema map[string]any, err error) {
+	t = elemType(t)
+	schema = map[string]any{}
+	switch {
+	case t == durationType:
+		schema["type"] = "integer"
+	case reflect.PointerTo(t).Implements(textUnmarshalerType):
+		schema["type"] = "string"
+	}
+	if len(schema) > 0 {
+		return
+	}
+	switch t.Kind() {
+	case reflect.String:
+		schema["type"] = "string"
+	case reflect.Bool:
+		schema["type"] = "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		schema["type"] = "integer"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		schema["type"] = "integer"
+		schema["minimum"] = 0
+	case reflect.Float32, reflect.Float64:
+		schema["type"] = "number"
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// []byte is base64 text
+			schema["type"] = "string"
+			return
+		}
+		schema["type"] = "array"
+		schema["items"], err = w.typeSchema(t.Elem())
+	case reflect.Map:
+		schema["type"] = "object"
+		schema["additionalProperties"], err = w.typeSchema(t.Elem())
+	case reflect.Struct:
+		if w.active[t] {
+			w.cycles[t] = true
+			return w.ref(t), nil
+		}
+		w.active[t] = true
+		err = w.structSchema(t, schema)
+		delete(w.active, t)
+		if w.cycles[t] && t != w.root {
+			w.defs[t.String()] = schema
+			schema = w.ref(t)
+		}
+	}
+	return
+}
+
+// structSchema adds the properties of the fields of t to schema
+func (w *schemaWalk) structSchema(t reflect.Type, schema map[string]any) (err error) {
+	var properties = map[string]any{}
+	var required = []string{}
+	for _, sf := range structFields(t) {
+		var name = jsonName(sf)
+		var property map[string]any
+		if property, err = w.typeSchema(sf.Type); err != nil {
+			return
+		}
+		if doc := docText(sf); len(doc) > 0 {
+			property["description"] = doc
+		}
+		if text := sf.Tag.Get("default"); len(text) > 0 {
+			var value = reflect.New(sf.Type).Elem()
+			if err = setValue(value, text); err != nil {
+				return fmt.Errorf("default %s: %w", name, err)
+			}
+			var encoded []byte
+			if encoded, err = json.Marshal(value.Interface()); err != nil {
+				return
+			}
+			property["default"] = decodeTree(encoded)
+		}
+		if len(sf.Tag.Get(DeprecatedTag)) > 0 && len(aliases(sf)) == 0 {
+			property["deprecated"] = true
+		}
+		var c constraint
+		if c, err = constraints(sf); err != nil {
+			return
+		}
+		constrain(property, c)
+		if c.Required {
+			required = append(required, name)
+		}
+		properties[name] = property
+		for _, alias := range aliases(sf) {
+			var deprecated = map[string]any{}
+			for key, value := range property {
+				deprecated[key] = value
+			}
+			deprecated["deprecated"] = true
+			deprecated["description"] = deprecation(sf, name)
+			properties[alias] = deprecated
+		}
+	}
+	schema["type"] = "object"
+	schema["properties"] = properties
+	schema["additionalProperties"] = false
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return
+}
+
+// constrain a property schema with the constraints of its field
+func constrain(property map[string]any, c constraint) {
+	if len(c.Enum) > 0 {
+		var values = make([]any, len(c.Enum))
+		for i, text := range c.Enum {
+			values[i] = text
+			if property["type"] != "string" {
+				// numbers and booleans are listed as json values
+				if value := decodeTree([]byte(text)); value != nil {
+					values[i] = value
+				}
+			}
+		}
+		property["enum"] = values
+	}
+	if c.Pattern != nil {
+		property["pattern"] = c.Pattern.String()
+	}
+	var lower, upper = "minimum", "maximum"
+	switch property["type"] {
+	case "string":
+		lower, upper = "minLength", "maxLength"
+	case "array":
+		lower, upper = "minItems", "maxItems"
+	case "object":
+		lower, upper = "minProperties", "maxProperties"
+	}
+	if c.Min != nil {
+		property[lower] = *c.Min
+	}
+	if c.Max != nil {
+		property[upper] = *c.Max
+	}
+}
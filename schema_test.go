@@ -0,0 +1,95 @@
+package autocfg
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type schemaConf struct {
+	VaultAddress string        `json:"vault-address" alias:"vault-addr" doc:"vault server url" required:"true" pattern:"^https://"`
+	Port         int           `json:"port" default:"8200" min:"1" max:"65535"`
+	Level        string        `json:"level,omitempty" enum:"debug,info"`
+	Timeout      time.Duration `json:"timeout"`
+	Tags         []string      `json:"tags" max:"4"`
+	Github       struct {
+		Mount string `json:"mount" default:"github"`
+	} `json:"github"`
+}
+
+func TestSchema(t *testing.T) {
+	var text, err = Schema(&schemaConf{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]any
+	if err = json.Unmarshal(text, &schema); err != nil {
+		t.Fatal(err)
+	}
+	var properties = schema["properties"].(map[string]any)
+	var expected = map[string]any{
+		"$schema":              SchemaDraft,
+		"required":             []any{"vault-address"},
+		"additionalProperties": false,
+	}
+	for key, want := range expected {
+		if !reflect.DeepEqual(schema[key], want) {
+			t.Errorf("%s expected %v got %v", key, want, schema[key])
+		}
+	}
+	var props = map[string]map[string]any{
+		"vault-address": {"type": "string", "description": "vault server url", "pattern": "^https://"},
+		"vault-addr":    {"type": "string", "description": "use vault-address", "pattern": "^https://", "deprecated": true},
+		"port":          {"type": "integer", "default": 8200.0, "minimum": 1.0, "maximum": 65535.0},
+		"level":         {"type": "string", "enum": []any{"debug", "info"}},
+		"timeout":       {"type": "integer"},
+		"tags":          {"type": "array", "items": map[string]any{"type": "string"}, "maxItems": 4.0},
+		"$schema":       {"type": "string"},
+	}
+	for name, want := range props {
+		if !reflect.DeepEqual(properties[name], map[string]any(want)) {
+			t.Errorf("%s expected %v got %v", name, want, properties[name])
+		}
+	}
+	var github = properties["github"].(map[string]any)["properties"].(map[string]any)["mount"]
+	if !reflect.DeepEqual(github, map[string]any{"type": "string", "default": "github"}) {
+		t.Errorf("github.mount got %v", github)
+	}
+}
+
+type schemaNode struct {
+	Name     string       `json:"name"`
+	Parent   *schemaNode  `json:"parent"`
+	Children []schemaNode `json:"children"`
+}
+
+type schemaTree struct {
+	Root  schemaNode  `json:"root"`
+	Outer *schemaTree `json:"outer"`
+}
+
+func TestSchemaCycle(t *testing.T) {
+	var text, err = Schema(&schemaTree{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]any
+	if err = json.Unmarshal(text, &schema); err != nil {
+		t.Fatal(err)
+	}
+	var properties = schema["properties"].(map[string]any)
+	if ref := properties["outer"].(map[string]any)["$ref"]; ref != "#" {
+		t.Errorf("expected outer to refer to the root got %v", properties["outer"])
+	}
+	var node = "#/$defs/autocfg.schemaNode"
+	if ref := properties["root"].(map[string]any)["$ref"]; ref != node {
+		t.Errorf("expected root to refer to %s got %v", node, properties["root"])
+	}
+	var def = schema["$defs"].(map[string]any)["autocfg.schemaNode"].(map[string]any)
+	var fields = def["properties"].(map[string]any)
+	if fields["parent"].(map[string]any)["$ref"] != node ||
+		fields["children"].(map[string]any)["items"].(map[string]any)["$ref"] != node {
+		t.Errorf("expected the node fields to refer to the node got %v", fields)
+	}
+}
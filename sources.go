@@ -0,0 +1,258 @@
+package autocfg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/davidwalter0/go-cfg"
+	eflag "github.com/davidwalter0/go-flag"
+)
+
+// SourcesTag names the struct tag restricting the sources of a field,
+// e.g. `sources:"file,env"` keeps a secret off the command line
+const SourcesTag = "sources"
+
+// ErrSourceNotAllowed is wrapped by errors for a source setting a
+// field its sources tag excludes
+var ErrSourceNotAllowed = errors.New("source not allowed")
+
+// sourceLayers maps sources tag names to layers
+var sourceLayers = map[string][]Layer{
+	"default":  {DefaultLayer},
+	"defaults": {DefaultLayer},
+	"file":     {FileLayer},
+	"files":    {FileLayer},
+	"env":      {EnvLayer, DotenvLayer},
+	"dotenv":   {DotenvLayer},
+	"flag":     {FlagLayer},
+	"flags":    {FlagLayer},
+}
+
+// allowed reports whether layer may set the field. Fields without a
+// sources tag accept every layer; default tags and the policy file
+// always apply.
+func allowed(sf reflect.StructField, layer Layer) bool {
+	var text, ok = sf.Tag.Lookup(SourcesTag)
+	if !ok || layer == DefaultLayer || layer == PolicyLayer {
+		return true
+	}
+	for _, name := range strings.Split(text, ",") {
+		for _, l := range sourceLayers[strings.TrimSpace(name)] {
+			if l == layer {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// notAllowed formats the error for source setting the field at l
+func notAllowed(l leaf, source Source) error {
+	return fmt.Errorf("%s %w: %s, accepts %s", l.Key(), ErrSourceNotAllowed, source, l.Field.Tag.Get(SourcesTag))
+}
+
+// restricted snapshots the leaves of obj the layer may not set
+func restricted(obj any, layer Layer) (prior map[string]reflect.Value) {
+	prior = map[string]reflect.Value{}
+	for _, l := range leaves(obj) {
+		if !allowed(l.Field, layer) {
+			var copied = reflect.New(l.Value.Type()).Elem()
+			copied.Set(l.Value)
+			prior[l.Key()] = copied
+		}
+	}
+	return
+}
+
+// revert restores the leaves in prior that set reports as set by a
+// layer they exclude, returning an error for each
+func revert(obj any, prior map[string]reflect.Value, set func(l leaf) (Source, bool)) (err error) {
+	if len(prior) == 0 {
+		return
+	}
+	var errs []error
+	for _, l := range leaves(obj) {
+		var value, ok = prior[l.Key()]
+		if !ok {
+			continue
+		}
+		if source, isSet := set(l); isSet {
+			l.Value.Set(value)
+			errs = append(errs, notAllowed(l, source))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// dropRestricted removes the keys of the json text of the file at path
+// that set fields excluding files, returning an error for each. The
+// text is returned unchanged when no key is removed.
+func dropRestricted(text []byte, obj any, path string) ([]byte, error) {
+	var tree = decodeTree(text)
+	var errs []error
+	for _, l := range leaves(obj) {
+		if !allowed(l.Field, FileLayer) && dropPath(tree, l.Path) {
+			errs = append(errs, notAllowed(l, Source{Layer: FileLayer, Name: path}))
+		}
+	}
+	if len(errs) == 0 {
+		return text, nil
+	}
+	if out, err := json.Marshal(tree); err == nil {
+		text = out
+	}
+	return text, errors.Join(errs...)
+}
+
+// dropPath deletes the key path from a decoded json tree, matching keys
+// like encoding/json, reporting whether it was present
+func dropPath(tree any, path []string) bool {
+	for i, name := range path {
+		var object, ok = tree.(map[string]any)
+		if !ok {
+			return false
+		}
+		var key string
+		if key, ok = findKey(object, name); !ok {
+			return false
+		}
+		if i == len(path)-1 {
+			delete(object, key)
+			return true
+		}
+		tree = object[key]
+	}
+	return false
+}
+
+// prepare the json text of the file at path for decoding into obj,
+// renaming alias keys then interpolating variables when expand is set
+func prepare(text []byte, obj any, path string, expand bool) ([]byte, error) {
+	text = migrate(text, obj, path)
+	if !expand {
+		return text, nil
+	}
+	var out, err = interpolateJSON(text, obj)
+	if err != nil {
+		return text, fmt.Errorf("%s %w", path, err)
+	}
+	return out, nil
+}
+
+// loadFile unmarshals the json text of the file at path into obj,
+// reporting unknown keys, dropping the keys of fields excluding files
+// and recording the fields it sets
+func loadFile(source []byte, obj any, path string, expand bool) (err error) {
+	var standard []byte
+	var origin func(int64) int64
+	if standard, origin, err = standardJSON(path, source); err != nil {
+		return
+	}
+	var unknown = unknownKeys(standard, obj, path)
+	var text []byte
+	if text, err = prepare(standard, obj, path, expand); err != nil {
+		return
+	}
+	var dropped error
+	text, dropped = dropRestricted(text, obj, path)
+	if err = json.Unmarshal(text, obj); err != nil {
+		return parseError(path, source, standard, origin, err)
+	}
+	recordFile(text, obj, path)
+	return errors.Join(dropped, unknown)
+}
+
+// revertLayer undoes fields whose recorded source is one of layers
+func revertLayer(obj any, prior map[string]reflect.Value, layers ...Layer) error {
+	return revert(obj, prior, func(l leaf) (Source, bool) {
+		var source, ok = provenance[l.Key()]
+		if !ok {
+			return source, false
+		}
+		for _, layer := range layers {
+			if source.Layer == layer {
+				delete(provenance, l.Key())
+				return source, true
+			}
+		}
+		return source, false
+	})
+}
+
+// hidden flag names of fields that exclude the flag layer
+var hidden = map[string]bool{}
+
+// hideRestricted omits flags of fields excluding flags from usage and
+// the env var names of fields excluding env
+func hideRestricted(obj any) {
+	var flags = flagsByAddr()
+	for _, l := range leaves(obj) {
+		var flag, ok = flags[l.Addr()]
+		if !ok {
+			continue
+		}
+		if !allowed(l.Field, FlagLayer) {
+			hidden[flag.Name] = true
+		}
+		if !allowed(l.Field, EnvLayer) {
+			flag.Usage = envLabel.ReplaceAllLiteralString(flag.Usage, "")
+		}
+	}
+	hideUsage()
+}
+
+// hideUsage installs usage when flags are hidden
+func hideUsage() {
+	if len(hidden) > 0 {
+		cfg.Usage = usage
+		eflag.Usage = usage
+		eflag.CommandLine.Usage = usage
+	}
+}
+
+// helpText mirrors the go-cfg help text for usage
+var helpText string
+
+// usage prints the go-cfg usage omitting hidden flags from the flag
+// defaults
+func usage() {
+	fmt.Fprintf(os.Stderr, "\nUsage of %s:\n", filepath.Base(os.Args[0]))
+	if len(helpText) > 0 {
+		fmt.Fprintf(os.Stderr, "\n%s\n\n", helpText)
+	}
+	var buffer bytes.Buffer
+	eflag.CommandLine.SetOutput(&buffer)
+	eflag.PrintDefaults()
+	eflag.CommandLine.SetOutput(nil)
+	os.Stderr.Write(visibleDefaults(buffer.Bytes()))
+}
+
+// visibleDefaults filters the flag defaults text, dropping the entry of
+// each hidden flag, the "  -name" line and its usage lines
+func visibleDefaults(text []byte) []byte {
+	var out []byte
+	var skip bool
+	for _, line := range bytes.SplitAfter(text, []byte("\n")) {
+		if rest, ok := bytes.CutPrefix(line, []byte("  -")); ok {
+			var name, _, _ = bytes.Cut(rest, []byte(" "))
+			name, _, _ = bytes.Cut(name, []byte("\t"))
+			skip = hidden[string(bytes.TrimSpace(name))]
+		}
+		if !skip {
+			out = append(out, line...)
+		}
+	}
+	return out
+}
+
+// setHelpText for go-cfg usage and the hidden flag usage
+func setHelpText(text string) {
+	helpText = text
+	cfg.HelpText(text)
+}
@@ -0,0 +1,75 @@
+package autocfg
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	eflag "github.com/davidwalter0/go-flag"
+)
+
+type sourcesConf struct {
+	SourcesSecret string `json:"sources-secret" sources:"file,env"`
+	SourcesToken  string `json:"sources-token" sources:"flag"`
+}
+
+func TestSourcesTagFlag(t *testing.T) {
+	var args = os.Args
+	defer func() {
+		os.Args = args
+		Reset()
+	}()
+	Reset()
+	os.Args = []string{"autocfg.test", "--sources-secret=leaked", "--sources-token=flag-token"}
+	var o = &sourcesConf{}
+	var err = Configure(o)
+	if !errors.Is(err, ErrSourceNotAllowed) {
+		t.Errorf("expected ErrSourceNotAllowed got %v", err)
+	}
+	if o.SourcesSecret != "" || o.SourcesToken != "flag-token" {
+		t.Errorf("flag should only set sources-token %+v", o)
+	}
+	if !hidden["sources-secret"] {
+		t.Error("sources-secret flag should be hidden from usage")
+	}
+}
+
+func TestSourcesTagFile(t *testing.T) {
+	defer Reset()
+	Reset()
+	var file = filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(file, []byte(`{"sources-secret": "file-secret", "sources-token": "file-token"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var o = &sourcesConf{SourcesToken: "prior"}
+	var err = LoadDirect(file, o)
+	if !errors.Is(err, ErrSourceNotAllowed) {
+		t.Errorf("expected ErrSourceNotAllowed got %v", err)
+	}
+	if o.SourcesSecret != "file-secret" || o.SourcesToken != "prior" {
+		t.Errorf("file should only set sources-secret %+v", o)
+	}
+	if _, ok := Provenance()["sources-token"]; ok {
+		t.Error("sources-token should have no source")
+	}
+}
+
+func TestVisibleDefaults(t *testing.T) {
+	defer Reset()
+	Reset()
+	var o = &sourcesConf{}
+	if err := Configure(o); err != nil {
+		t.Fatal(err)
+	}
+	var buffer bytes.Buffer
+	eflag.CommandLine.SetOutput(&buffer)
+	eflag.PrintDefaults()
+	eflag.CommandLine.SetOutput(nil)
+	var text = string(visibleDefaults(buffer.Bytes()))
+	if strings.Contains(text, "-sources-secret") || !strings.Contains(text, "-sources-token") {
+		t.Errorf("expected only the sources-secret flag hidden got\n%s", text)
+	}
+}
@@ -0,0 +1,151 @@
+package autocfg
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrUnknownKey is wrapped by errors for config file keys that match
+// no field, reported when Strict is set and warned otherwise
+var ErrUnknownKey = errors.New("unknown key")
+
+// unknownKey found in a config file and the closest known name
+type unknownKey struct {
+	Path       []string
+	Suggestion string
+}
+
+// unknownKeys reports each key of the json text of the file at path
+// that encoding/json would ignore decoding into obj, with its line and
+// the closest field name
+func unknownKeys(text []byte, obj any, path string) (err error) {
+	var tree = decodeTree(text)
+	if tree == nil {
+		return
+	}
+	var lines = keyLines(text)
+	var errs []error
+	for _, unknown := range unknownTree(tree, reflect.TypeOf(obj), nil) {
+		var hint string
+		if len(unknown.Suggestion) > 0 {
+			hint = fmt.Sprintf(", did you mean %q?", unknown.Suggestion)
+		}
+		var at = location(path, lines, unknown.Path)
+		var key = strings.Join(unknown.Path, ".")
+		if Strict {
+			errs = append(errs, fmt.Errorf("%s: %w %q%s", at, ErrUnknownKey, key, hint))
+			continue
+		}
+		warn("%s: %s %q%s", at, ErrUnknownKey, key, hint)
+	}
+	return errors.Join(errs...)
+}
+
+// unknownTree walks a decoded json value alongside the Go type it
+// decodes into, listing object keys without a field in sorted order.
+// A top level "$schema" editor reference and the sections of other
+// multicall calls are known.
+func unknownTree(node any, t reflect.Type, at []string) (unknown []unknownKey) {
+	t = elemType(t)
+	if t == nil {
+		return
+	}
+	var key = func(k string) []string {
+		return append(append([]string{}, at...), k)
+	}
+	switch v := node.(type) {
+	case map[string]any:
+		var keys = make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		if t.Kind() == reflect.Map {
+			for _, k := range keys {
+				unknown = append(unknown, unknownTree(v[k], t.Elem(), key(k))...)
+			}
+			return
+		}
+		if t.Kind() != reflect.Struct || isScalarStruct(t) {
+			return
+		}
+		for _, k := range keys {
+			if sf, ok := lookupField(t, k); ok {
+				unknown = append(unknown, unknownTree(v[k], sf.Type, key(k))...)
+				continue
+			}
+			if isAlias(t, k) || len(at) == 0 && (k == "$schema" || view != nil && view.others[k]) {
+				continue
+			}
+			unknown = append(unknown, unknownKey{Path: key(k), Suggestion: suggest(k, fieldNames(t))})
+		}
+	case []any:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return
+		}
+		for i, value := range v {
+			unknown = append(unknown, unknownTree(value, t.Elem(), key(strconv.Itoa(i)))...)
+		}
+	}
+	return
+}
+
+// isAlias reports a key naming an alias of a field of t
+func isAlias(t reflect.Type, key string) bool {
+	for _, sf := range structFields(t) {
+		for _, alias := range aliases(sf) {
+			if strings.EqualFold(alias, key) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fieldNames of the json visible fields of t
+func fieldNames(t reflect.Type) (names []string) {
+	for _, sf := range structFields(t) {
+		names = append(names, jsonName(sf))
+	}
+	return
+}
+
+// suggest the name closest to key by edit distance, empty when none
+// is close enough to be a likely typo
+func suggest(key string, names []string) (best string) {
+	var limit = len(key)/3 + 1
+	if limit > 3 {
+		limit = 3
+	}
+	for _, name := range names {
+		if d := distance(strings.ToLower(key), strings.ToLower(name)); d <= limit {
+			best, limit = name, d-1
+		}
+	}
+	return
+}
+
+// distance is the Levenshtein edit distance between a and b
+func distance(a, b string) int {
+	var previous = make([]int, len(b)+1)
+	var current = make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			var cost = 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,59 @@
+package autocfg
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type strictConf struct {
+	Role  string `json:"role"`
+	Vault struct {
+		Address string `json:"address" alias:"addr"`
+	} `json:"vault"`
+}
+
+func TestUnknownKeys(t *testing.T) {
+	defer func() {
+		Strict = false
+		Reset()
+	}()
+	var file = filepath.Join(t.TempDir(), "config.json")
+	var text = "{\n  \"rol\": \"reader\",\n  \"vault\": {\n    \"addr\": \"x\",\n    \"adress\": \"y\"\n  },\n  \"zzz\": 1\n}\n"
+	if err := os.WriteFile(file, []byte(text), 0600); err != nil {
+		t.Fatal(err)
+	}
+	Reset()
+	if err := LoadDirect(file, &strictConf{}); err != nil {
+		t.Fatal(err)
+	}
+	var expected = []string{
+		file + `:2: unknown key "rol", did you mean "role"?`,
+		file + `:5: unknown key "vault.adress", did you mean "address"?`,
+		file + `:7: unknown key "zzz"`,
+	}
+	var warnings = Warnings()[:3] // then the addr alias deprecation
+	if strings.Join(warnings, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(warnings, "\n"))
+	}
+	Reset()
+	Strict = true
+	var o = &strictConf{}
+	if err := LoadDirect(file, o); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected ErrUnknownKey got %v", err)
+	}
+	if o.Vault.Address != "x" {
+		t.Errorf("known keys should still load %+v", o)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	var names = []string{"vault-address", "role", "secret"}
+	for key, want := range map[string]string{"vault-adress": "vault-address", "rloe": "role", "x": "", "password": ""} {
+		if got := suggest(key, names); got != want {
+			t.Errorf("suggest %s expected %q got %q", key, want, got)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package autocfg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Format of a generated configuration template
+type Format string
+
+const (
+	// FormatJSON plain json, doc tags are dropped
+	FormatJSON Format = "json"
+	// FormatJSONC json with // doc comments
+	FormatJSONC Format = "jsonc"
+	// FormatYAML yaml with # doc comments
+	FormatYAML Format = "yaml"
+	// FormatTOML toml with # doc comments
+	FormatTOML Format = "toml"
+)
+
+// TemplateFormat for a file path from its extension. .json files are
+// FormatJSONC when JSONC is set and FormatJSON otherwise.
+func TemplateFormat(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonc", ".json5":
+		return FormatJSONC
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	}
+	if JSONC {
+		return FormatJSONC
+	}
+	return FormatJSON
+}
+
+// node of a configuration template, an object with children or a
+// value decoded from json
+type node struct {
+	Key      string
+	Doc      string
+	Object   bool
+	Children []node
+	Value    any
+}
+
+// templateNodes lists every json visible field of v, omitempty fields
+// included, zero values replaced by their default tag
+func templateNodes(v reflect.Value) (nodes []node, err error) {
+	for _, sf := range structFields(v.Type()) {
+		var fv reflect.Value
+		if fv, err = v.FieldByIndexErr(sf.Index); err != nil {
+			// a nil embedded pointer, its fields are zero
+			fv = reflect.New(v.Type().FieldByIndex(sf.Index).Type).Elem()
+			err = nil
+		}
+		var n = node{Key: jsonName(sf), Doc: docText(sf)}
+		if text := sf.Tag.Get("default"); len(text) > 0 && fv.IsZero() {
+			var dv = reflect.New(fv.Type()).Elem()
+			if err = setValue(dv, text); err != nil {
+				return nil, fmt.Errorf("default %s: %w", n.Key, err)
+			}
+			fv = dv
+		}
+		switch {
+		case fv.Kind() == reflect.Map && fv.IsNil():
+			fv = reflect.MakeMap(fv.Type())
+		case fv.Kind() == reflect.Slice && fv.IsNil():
+			fv = reflect.MakeSlice(fv.Type(), 0, 0)
+		}
+		var t = elemType(fv.Type())
+		if t.Kind() == reflect.Struct && !isScalarStruct(t) {
+			for fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					fv = reflect.New(fv.Type().Elem())
+				}
+				fv = fv.Elem()
+			}
+			n.Object = true
+			if n.Children, err = templateNodes(fv); err != nil {
+				return
+			}
+			nodes = append(nodes, n)
+			continue
+		}
+		var text []byte
+		if text, err = json.Marshal(fv.Interface()); err != nil {
+			return nil, fmt.Errorf("%s: %w", n.Key, err)
+		}
+		n.Value = decodeTree(text)
+		nodes = append(nodes, n)
+	}
+	return
+}
+
+// WriteTemplate writes a configuration template for obj to w. Every
+// field is included with its current value, or its default tag value
+// when zero, and commented with its doc tag in formats with comments.
+func WriteTemplate(w io.Writer, obj any, format Format) (err error) {
+	defer Trace.ScopedTrace()()
+	if !isPtr(obj) {
+		return fmt.Errorf("arg obj any [%T]: object is not a pointer to struct", obj)
+	}
+	var nodes []node
+	if nodes, err = templateNodes(reflect.ValueOf(obj).Elem()); err != nil {
+		return
+	}
+	var buffer bytes.Buffer
+	switch format {
+	case FormatJSON, FormatJSONC:
+		if len(SchemaRef) > 0 {
+			nodes = append([]node{{Key: "$schema", Value: SchemaRef}}, nodes...)
+		}
+		buffer.WriteString("{\n")
+		writeJSONC(&buffer, nodes, 1, format == FormatJSONC)
+		buffer.WriteString("}\n")
+	case FormatYAML:
+		writeYAML(&buffer, nodes, 0)
+	case FormatTOML:
+		writeTOML(&buffer, nodes, nil)
+	default:
+		return fmt.Errorf("unknown template format %q", format)
+	}
+	_, err = w.Write(buffer.Bytes())
+	return
+}
+
+// GenerateTemplate writes a configuration template for obj to path in
+// the TemplateFormat of the path. An existing file is replaced only
+// when overwrite is set.
+func GenerateTemplate(path string, obj any, overwrite bool) (err error) {
+	defer Trace.ScopedTrace()()
+	if _, err = os.Stat(path); err == nil && !overwrite {
+		return fmt.Errorf("%s %w", path, fs.ErrExist)
+	}
+	var buffer bytes.Buffer
+	if err = WriteTemplate(&buffer, obj, TemplateFormat(path)); err != nil {
+		return
+	}
+	return os.WriteFile(path, buffer.Bytes(), 0644)
+}
+
+// comment lines of a doc tag with a comment prefix at indent
+func comment(w *bytes.Buffer, doc, indent, prefix string) {
+	for _, line := range strings.Split(strings.TrimSpace(doc), "\n") {
+		if line = strings.TrimSpace(line); len(line) > 0 {
+			fmt.Fprintf(w, "%s%s %s\n", indent, prefix, line)
+		}
+	}
+}
+
+func writeJSONC(w *bytes.Buffer, nodes []node, depth int, comments bool) {
+	var indent = strings.Repeat("  ", depth)
+	for i, n := range nodes {
+		if comments {
+			comment(w, n.Doc, indent, "//")
+		}
+		var key = encode(n.Key, "", "")
+		fmt.Fprintf(w, "%s%s: ", indent, key)
+		if n.Object {
+			w.WriteString("{\n")
+			writeJSONC(w, n.Children, depth+1, comments)
+			fmt.Fprintf(w, "%s}", indent)
+		} else {
+			var text = encode(n.Value, indent, "  ")
+			w.Write(text)
+		}
+		if i < len(nodes)-1 {
+			w.WriteString(",")
+		}
+		w.WriteString("\n")
+	}
+}
+
+func writeYAML(w *bytes.Buffer, nodes []node, depth int) {
+	var indent = strings.Repeat("  ", depth)
+	for _, n := range nodes {
+		comment(w, n.Doc, indent, "#")
+		var key = encode(n.Key, "", "")
+		if bareKey.MatchString(n.Key) {
+			key = []byte(n.Key)
+		}
+		if n.Object {
+			fmt.Fprintf(w, "%s%s:\n", indent, key)
+			writeYAML(w, n.Children, depth+1)
+			continue
+		}
+		// json values are yaml flow values
+		var text = encode(n.Value, "", "")
+		fmt.Fprintf(w, "%s%s: %s\n", indent, key, text)
+	}
+}
+
+// encode value as json without the html escapes of json.Marshal, the
+// lines after the first start with prefix and nest by indent when set
+func encode(value any, prefix, indent string) []byte {
+	var buffer bytes.Buffer
+	var encoder = json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent(prefix, indent)
+	_ = encoder.Encode(value)
+	return bytes.TrimSuffix(buffer.Bytes(), []byte("\n"))
+}
+
+var bareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// tomlKey quotes keys toml does not accept bare
+func tomlKey(key string) string {
+	if bareKey.MatchString(key) {
+		return key
+	}
+	return string(encode(key, "", ""))
+}
+
+// writeTOML writes the values of a table then its sub tables
+func writeTOML(w *bytes.Buffer, nodes []node, at []string) {
+	for _, n := range nodes {
+		if n.Object {
+			continue
+		}
+		comment(w, n.Doc, "", "#")
+		if n.Value == nil {
+			// toml has no null, an unset pointer is left unset
+			fmt.Fprintf(w, "# %s =\n", tomlKey(n.Key))
+			continue
+		}
+		fmt.Fprintf(w, "%s = %s\n", tomlKey(n.Key), tomlValue(n.Value))
+	}
+	for _, n := range nodes {
+		if !n.Object {
+			continue
+		}
+		var table = append(append([]string{}, at...), tomlKey(n.Key))
+		w.WriteString("\n")
+		comment(w, n.Doc, "", "#")
+		fmt.Fprintf(w, "[%s]\n", strings.Join(table, "."))
+		writeTOML(w, n.Children, table)
+	}
+}
+
+// tomlValue formats a decoded json value, objects as inline tables
+func tomlValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return `""`
+	case []any:
+		var items = make([]string, len(v))
+		for i, item := range v {
+			items[i] = tomlValue(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]any:
+		var keys = make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		var items = make([]string, len(keys))
+		for i, key := range keys {
+			items[i] = tomlKey(key) + " = " + tomlValue(v[key])
+		}
+		if len(items) == 0 {
+			return "{}"
+		}
+		return "{ " + strings.Join(items, ", ") + " }"
+	}
+	return string(encode(value, "", ""))
+}
@@ -0,0 +1,93 @@
+package autocfg
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type templateConf struct {
+	Role    string            `json:"role" doc:"approle role"`
+	Debug   bool              `json:"debug,omitempty"`
+	Retries int               `json:"retries,omitempty" default:"3" doc:"login attempts"`
+	Labels  map[string]string `json:"labels"`
+	Github  struct {
+		Mount string `json:"mount" default:"github" doc:"auth mount"`
+	} `json:"github" doc:"Github authentication"`
+}
+
+func TestWriteTemplate(t *testing.T) {
+	var o = &templateConf{Role: "reader"}
+	var expected = map[Format]string{
+		FormatJSONC: `{
+  // approle role
+  "role": "reader",
+  "debug": false,
+  // login attempts
+  "retries": 3,
+  "labels": {},
+  // Github authentication
+  "github": {
+    // auth mount
+    "mount": "github"
+  }
+}
+`,
+		FormatYAML: `# approle role
+role: "reader"
+debug: false
+# login attempts
+retries: 3
+labels: {}
+# Github authentication
+github:
+  # auth mount
+  mount: "github"
+`,
+		FormatTOML: `# approle role
+role = "reader"
+debug = false
+# login attempts
+retries = 3
+labels = {}
+
+# Github authentication
+[github]
+# auth mount
+mount = "github"
+`,
+	}
+	for format, want := range expected {
+		var buffer bytes.Buffer
+		if err := WriteTemplate(&buffer, o, format); err != nil {
+			t.Fatal(err)
+		}
+		if buffer.String() != want {
+			t.Errorf("%s expected\n%s\ngot\n%s", format, want, buffer.String())
+		}
+	}
+}
+
+func TestGenerateTemplate(t *testing.T) {
+	defer Reset()
+	Reset()
+	var path = filepath.Join(t.TempDir(), "config.jsonc")
+	var o = &templateConf{Role: "reader"}
+	if err := GenerateTemplate(path, o, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := GenerateTemplate(path, o, false); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("expected an exists error got %v", err)
+	}
+	var loaded = &templateConf{}
+	if err := LoadDirect(path, loaded); err != nil {
+		t.Fatal(err)
+	}
+	o.Retries, o.Labels, o.Github.Mount = 3, map[string]string{}, "github"
+	if !reflect.DeepEqual(o, loaded) {
+		t.Errorf("expected %+v got %+v", o, loaded)
+	}
+}
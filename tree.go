@@ -0,0 +1,248 @@
+package autocfg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	eflag "github.com/davidwalter0/go-flag"
+)
+
+// CmdTag names a subcommand struct field of a command tree,
+// `cmd:"login"`
+const CmdTag = "cmd"
+
+// Runner is a command of a command tree, a node struct with a Run
+// method on its pointer
+type Runner interface {
+	Run(args []string) error
+}
+
+// isCmd reports a subcommand field
+func isCmd(sf reflect.StructField) bool {
+	return len(sf.Tag.Get(CmdTag)) > 0 && sf.Type.Kind() == reflect.Struct
+}
+
+// subcommands of a node type in field order
+func subcommands(t reflect.Type) (list []reflect.StructField) {
+	for _, sf := range structFields(t) {
+		if isCmd(sf) {
+			list = append(list, sf)
+		}
+	}
+	return
+}
+
+// subcommand of t named name
+func subcommand(t reflect.Type, name string) (reflect.StructField, bool) {
+	for _, sf := range subcommands(t) {
+		if sf.Tag.Get(CmdTag) == name {
+			return sf, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+/*
+treeView builds the view of a command path: the options of each node,
+its fields other than subcommands, and the subcommand field of the
+next node on path. transfer copies the values between a view and the
+tree, to the tree when out is set.
+*/
+func treeView(t reflect.Type, path []reflect.StructField) (view reflect.Type, transfer func(view, tree reflect.Value, out bool)) {
+	var fields []reflect.StructField
+	var index [][]int
+	var names = map[string]bool{}
+	var add = func(sf reflect.StructField) {
+		var name = sf.Name
+		for names[name] {
+			name += "_"
+		}
+		names[name] = true
+		fields = append(fields, reflect.StructField{Name: name, Type: sf.Type, Tag: sf.Tag})
+	}
+	for _, sf := range structFields(t) {
+		if isCmd(sf) || viaPointer(t, sf.Index) {
+			continue
+		}
+		index = append(index, sf.Index)
+		add(sf)
+	}
+	var next func(view, tree reflect.Value, out bool)
+	if len(path) > 0 {
+		var child reflect.Type
+		child, next = treeView(path[0].Type, path[1:])
+		add(reflect.StructField{Name: path[0].Name, Type: child, Tag: path[0].Tag})
+	}
+	transfer = func(view, tree reflect.Value, out bool) {
+		for i, at := range index {
+			if out {
+				tree.FieldByIndex(at).Set(view.Field(i))
+			} else {
+				view.Field(i).Set(tree.FieldByIndex(at))
+			}
+		}
+		if next != nil {
+			next(view.Field(len(index)), tree.FieldByIndex(path[0].Index), out)
+		}
+	}
+	return reflect.StructOf(fields), transfer
+}
+
+// valueFlags of the options of a node, the flags taking a value
+func valueFlags(t reflect.Type) (flags map[string]bool) {
+	flags = map[string]bool{}
+	var view, _ = treeView(t, nil)
+	for _, l := range leaves(reflect.New(view).Interface()) {
+		flags[cfgFlagName(l.Path[len(l.Path)-1:])] = l.Field.Type.Kind() != reflect.Bool
+	}
+	return
+}
+
+// treeClash reports options of the nodes of a view sharing a flag
+// name, flags and env vars are named for the option alone so a parent
+// and child option of the same name cannot both be defined
+func treeClash(view reflect.Type) error {
+	var seen = map[string]string{}
+	for _, l := range leaves(reflect.New(view).Interface()) {
+		var name = cfgFlagName(l.Path[len(l.Path)-1:])
+		if key, ok := seen[name]; ok {
+			return fmt.Errorf("options %s and %s share the flag --%s, rename one", key, l.Key(), name)
+		}
+		seen[name] = l.Key()
+	}
+	return nil
+}
+
+// treePath finds the subcommands named in args from the root type t,
+// skipping the flags of the nodes found, the remaining arguments are
+// the flags and arguments of the command, word the first argument
+func treePath(t reflect.Type, args []string) (path []reflect.StructField, rest []string, word string) {
+	var flags = valueFlags(t)
+	for i := 0; i < len(args); i++ {
+		var arg = args[i]
+		switch {
+		case arg == "--":
+			return path, append(rest, args[i:]...), ""
+		case strings.HasPrefix(arg, "-"):
+			rest = append(rest, arg)
+			var name, _, value = strings.Cut(strings.TrimLeft(arg, "-"), "=")
+			if !value && flags[name] && i+1 < len(args) {
+				i++
+				rest = append(rest, args[i])
+			}
+		default:
+			var sf, ok = subcommand(t, arg)
+			if !ok {
+				return path, append(rest, args[i:]...), arg
+			}
+			path = append(path, sf)
+			t = sf.Type
+			for name, takesValue := range valueFlags(t) {
+				flags[name] = takesValue
+			}
+		}
+	}
+	return
+}
+
+// treeHelp of a node, its doc text and subcommands
+func treeHelp(doc string, t reflect.Type) string {
+	var text = doc
+	var list = subcommands(t)
+	if len(list) == 0 {
+		return text
+	}
+	var width int
+	for _, sf := range list {
+		width = max(width, len(sf.Tag.Get(CmdTag)))
+	}
+	text = strings.TrimSpace(text + "\n\nCommands:")
+	for _, sf := range list {
+		text += fmt.Sprintf("\n  %-*s  %s", width, sf.Tag.Get(CmdTag), firstLine(docText(sf)))
+	}
+	return text
+}
+
+/*
+DispatchTree runs the command of a tree of structs named by args, the
+program name then subcommand names, flags and arguments. A struct
+field with a cmd tag is a subcommand, the other fields are options. A
+command reads the options of each node on its path, so parent options
+are inherited and their flags set at any level. The files hold each
+node in the section of its json name, flags and env vars are named for
+the fields as by Configure, so the options on a path need distinct
+names, a parent and child option of the same name is an error. The
+command runs when its node is a Runner. A node without Run prints
+its usage, listing its subcommands, and returns ErrUnknownCall. -h
+prints the usage of a node.
+
+	tool --vault-address https://vault:8200 vault login approle --role reader
+*/
+func DispatchTree(root any, args []string) (err error) {
+	defer Trace.ScopedTrace()()
+	if !isPtr(root) {
+		return fmt.Errorf("arg root any [%T]: object is not a pointer to struct", root)
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("dispatch: args has no program name")
+	}
+	var t = reflect.TypeOf(root).Elem()
+	var path, rest, word = treePath(t, args[1:])
+	var program = args[0]
+	var node = reflect.ValueOf(root).Elem()
+	var doc string
+	for _, sf := range path {
+		program += " " + sf.Tag.Get(CmdTag)
+		node = node.FieldByIndex(sf.Index)
+		doc = docText(sf)
+	}
+	var runner, _ = node.Addr().Interface().(Runner)
+	var callArgs = rest
+	var help bool
+	if runner == nil {
+		// a node without Run prints its usage
+		callArgs = []string{"--help"}
+		for _, arg := range rest {
+			if arg == "--" || arg == word {
+				break
+			}
+			var name, _, _ = strings.Cut(strings.TrimLeft(arg, "-"), "=")
+			help = help || strings.HasPrefix(arg, "-") && (name == "h" || name == "help")
+		}
+	}
+
+	var view, transfer = treeView(t, path)
+	if err = treeClash(view); err != nil {
+		return fmt.Errorf("%s %w", program, err)
+	}
+	var obj = reflect.New(view)
+	transfer(obj.Elem(), reflect.ValueOf(root).Elem(), false)
+	var priorArgs = os.Args
+	defer func() { os.Args = priorArgs }()
+	os.Args = append([]string{program}, callArgs...)
+	Reset()
+	setHelpText(treeHelp(doc, node.Type()))
+	eflag.CommandLine.Usage = usage
+	err = Configure(obj.Interface())
+	transfer(obj.Elem(), reflect.ValueOf(root).Elem(), true)
+	if err != nil {
+		return fmt.Errorf("%s %w", program, err)
+	}
+	switch {
+	case runner == nil && help:
+		return
+	case runner == nil && len(word) > 0:
+		return fmt.Errorf("%s %q %w", program, word, ErrUnknownCall)
+	case runner == nil:
+		return fmt.Errorf("%s: no command given, %w", program, ErrUnknownCall)
+	case errors.Is(flagErr, eflag.ErrHelp):
+		// the flag parser printed the usage
+		return
+	case flagErr != nil:
+		return fmt.Errorf("%s %w", program, flagErr)
+	}
+	return runner.Run(eflag.Args())
+}
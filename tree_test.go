@@ -0,0 +1,142 @@
+package autocfg
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	eflag "github.com/davidwalter0/go-flag"
+	"github.com/mitchellh/go-homedir"
+)
+
+type treeRoot struct {
+	Debug bool      `json:"debug"`
+	Addr  string    `json:"addr"`
+	Vault treeVault `json:"vault" cmd:"vault" doc:"vault commands"`
+}
+
+type treeVault struct {
+	Namespace string    `json:"namespace"`
+	Login     treeLogin `json:"login" cmd:"login" doc:"log in to vault"`
+}
+
+type treeLogin struct {
+	Approle treeApprole `json:"approle" cmd:"approle" doc:"log in with an approle"`
+	Github  treeGithub  `json:"github" cmd:"github" doc:"log in with a github token"`
+}
+
+type treeApprole struct {
+	Role  string `json:"role"`
+	Mount string `json:"mount"`
+	args  []string
+}
+
+func (c *treeApprole) Run(args []string) error {
+	c.args = args
+	return nil
+}
+
+type treeGithub struct {
+	Token string `json:"token"`
+}
+
+func (c *treeGithub) Run(args []string) error { return nil }
+
+func TestDispatchTree(t *testing.T) {
+	var args, program, saved = os.Args, Program(), GetMode()
+	homedir.DisableCache = true
+	defer func() {
+		homedir.DisableCache = false
+		os.Args = args
+		SetProgram(program)
+		_, _ = SetMode(saved)
+		Reset()
+	}()
+	var home = t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("AUTOCFG_FILENAME", "")
+	_, _ = SetMode(Union | Direct)
+	SetProgram("tree-test")
+	var dir = filepath.Join(home, ".config", "tree-test")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	var text = `{"addr": "https://file", "vault": {"namespace": "ns", "login": {"approle": {"role": "file-role"}}}}`
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(text), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var root treeRoot
+	if err := DispatchTree(&root, []string{"tool", "vault", "login", "approle"}); err != nil {
+		t.Fatal(err)
+	}
+	if root.Addr != "https://file" || root.Vault.Namespace != "ns" || root.Vault.Login.Approle.Role != "file-role" {
+		t.Errorf("expected each node from its file section got %+v", root)
+	}
+	root = treeRoot{}
+	var cmd = []string{"tool", "vault", "--addr", "https://flag", "login", "approle", "--role", "reader", "--debug", "extra"}
+	if err := DispatchTree(&root, cmd); err != nil {
+		t.Fatal(err)
+	}
+	var approle = root.Vault.Login.Approle
+	if root.Addr != "https://flag" || !root.Debug || approle.Role != "reader" || strings.Join(approle.args, " ") != "extra" {
+		t.Errorf("expected parent flags set below their node got %+v", root)
+	}
+	if eflag.Lookup("token") != nil || eflag.Lookup("namespace") == nil {
+		t.Errorf("expected the flags of the approle path only")
+	}
+
+	if err := DispatchTree(&root, []string{"tool", "vault"}); !errors.Is(err, ErrUnknownCall) {
+		t.Errorf("expected ErrUnknownCall without a command got %v", err)
+	}
+	if err := DispatchTree(&root, []string{"tool", "vault", "--addr", "x", "logout"}); !errors.Is(err, ErrUnknownCall) || !strings.Contains(err.Error(), `"logout"`) {
+		t.Errorf("expected logout unknown got %v", err)
+	}
+	if err := DispatchTree(&root, []string{"tool", "vault", "-h"}); err != nil {
+		t.Errorf("expected the vault usage got %v", err)
+	}
+	if help := treeHelp("vault commands", reflect.TypeOf(treeLogin{})); !strings.Contains(help, "approle  log in with an approle") {
+		t.Errorf("expected the login subcommands in\n%s", help)
+	}
+}
+
+type clashRoot struct {
+	Mount string     `json:"mount"`
+	Login clashLogin `json:"login" cmd:"login"`
+}
+
+type clashLogin struct {
+	Mount string `json:"mount"`
+}
+
+func (c *clashLogin) Run(args []string) error { return nil }
+
+func TestDispatchTreeClash(t *testing.T) {
+	var args = os.Args
+	defer func() {
+		os.Args = args
+		Reset()
+	}()
+	var root clashRoot
+	var err = DispatchTree(&root, []string{"tool", "login", "--mount", "approle"})
+	if err == nil || !strings.Contains(err.Error(), "options mount and login.mount share the flag --mount") {
+		t.Errorf("expected the parent and child mount options to clash got %v", err)
+	}
+	if root.Mount != "" || root.Login.Mount != "" {
+		t.Errorf("expected no option set on a clash got %+v", root)
+	}
+}
+
+func TestTreePath(t *testing.T) {
+	var path, rest, word = treePath(reflect.TypeOf(treeRoot{}), strings.Fields("--addr login vault --debug login github --token t arg"))
+	var names []string
+	for _, sf := range path {
+		names = append(names, sf.Tag.Get(CmdTag))
+	}
+	if strings.Join(names, " ") != "vault login github" || strings.Join(rest, " ") != "--addr login --debug --token t arg" || word != "arg" {
+		t.Errorf("unexpected path %v rest %v word %q", names, rest, word)
+	}
+}
@@ -0,0 +1,156 @@
+package autocfg
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// validation struct tags, shared by Schema and the config checks
+//
+//	Port  int    `json:"port" required:"true" min:"1" max:"65535"`
+//	Level string `json:"level" enum:"debug,info,warn,error"`
+//	Name  string `json:"name" pattern:"^[a-z][a-z0-9-]*$"`
+//
+// min and max bound numbers, the length of strings and the items of
+// slices and maps
+const (
+	// RequiredTag "true" requires a non zero value, also a go-cfg tag
+	RequiredTag = "required"
+	// EnumTag lists the allowed values separated by commas
+	EnumTag = "enum"
+	// MinTag is the lower bound
+	MinTag = "min"
+	// MaxTag is the upper bound
+	MaxTag = "max"
+	// PatternTag is a regular expression string values must match
+	PatternTag = "pattern"
+)
+
+// constraint of a field from its validation tags
+type constraint struct {
+	Required bool
+	Enum     []string
+	Min, Max *float64
+	Pattern  *regexp.Regexp
+}
+
+// constraints parses the validation tags of a field
+func constraints(sf reflect.StructField) (c constraint, err error) {
+	c.Required, _ = strconv.ParseBool(sf.Tag.Get(RequiredTag))
+	if text := sf.Tag.Get(EnumTag); len(text) > 0 {
+		for _, value := range strings.Split(text, ",") {
+			c.Enum = append(c.Enum, strings.TrimSpace(value))
+		}
+	}
+	var bound = func(tag string) (*float64, error) {
+		var text = sf.Tag.Get(tag)
+		if len(text) == 0 {
+			return nil, nil
+		}
+		var f, err = strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s tag of %s: %w", tag, sf.Name, err)
+		}
+		return &f, nil
+	}
+	if c.Min, err = bound(MinTag); err != nil {
+		return
+	}
+	if c.Max, err = bound(MaxTag); err != nil {
+		return
+	}
+	if text := sf.Tag.Get(PatternTag); len(text) > 0 {
+		if c.Pattern, err = regexp.Compile(text); err != nil {
+			return c, fmt.Errorf("pattern tag of %s: %w", sf.Name, err)
+		}
+	}
+	return
+}
+
+// docText of a field from its doc tag, or help and usage like go-cfg
+func docText(sf reflect.StructField) string {
+	for _, tag := range []string{"doc", "help", "usage"} {
+		if text := sf.Tag.Get(tag); len(text) > 0 {
+			return text
+		}
+	}
+	return ""
+}
+
+// ErrInvalid is wrapped by errors for values failing their
+// validation tags
+var ErrInvalid = errors.New("invalid value")
+
+// Validate checks the fields of obj against their required, enum, min,
+// max and pattern tags, returning an error for each failure. Zero
+// values without a recorded source are unset.
+func Validate(obj any) (err error) {
+	defer Trace.ScopedTrace()()
+	var errs []error
+	for _, l := range leaves(obj) {
+		var c, cerr = constraints(l.Field)
+		if cerr != nil {
+			errs = append(errs, cerr)
+			continue
+		}
+		var _, set = provenance[l.Key()]
+		if reason := c.check(l.Value, set); len(reason) > 0 {
+			errs = append(errs, fmt.Errorf("%s: %w %s", l.Key(), ErrInvalid, reason))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// check a value, returning the reason it fails, empty when it passes.
+// A zero value no source set is unset, only checked when required.
+func (c constraint) check(v reflect.Value, set bool) string {
+	if v.IsZero() {
+		if c.Required {
+			return "(required)"
+		}
+		if !set {
+			return ""
+		}
+	}
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	var text = fmt.Sprint(v.Interface())
+	if len(c.Enum) > 0 {
+		var found bool
+		for _, value := range c.Enum {
+			found = found || value == text
+		}
+		if !found {
+			return fmt.Sprintf("%q, one of %s", text, strings.Join(c.Enum, ", "))
+		}
+	}
+	if c.Pattern != nil && v.Kind() == reflect.String && !c.Pattern.MatchString(text) {
+		return fmt.Sprintf("%q, must match %s", text, c.Pattern)
+	}
+	var size float64
+	var what = ""
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		size, what = float64(v.Len()), " length"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		size = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		size = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		size = v.Float()
+	default:
+		return ""
+	}
+	if c.Min != nil && size < *c.Min {
+		return fmt.Sprintf("%s%s below minimum %v", text, what, *c.Min)
+	}
+	if c.Max != nil && size > *c.Max {
+		return fmt.Sprintf("%s%s above maximum %v", text, what, *c.Max)
+	}
+	return ""
+}
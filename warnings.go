@@ -0,0 +1,28 @@
+package autocfg
+
+import (
+	"fmt"
+	"os"
+)
+
+// warnings reported while configuring
+var warnings []string
+
+// Warnings returns the warnings reported since the last Reset
+func Warnings() []string {
+	defer Trace.ScopedTrace()()
+	return append([]string(nil), warnings...)
+}
+
+// ResetWarnings clears the reported warnings
+func ResetWarnings() {
+	defer Trace.ScopedTrace()()
+	warnings = nil
+}
+
+// warn records a warning and reports it on stderr
+func warn(format string, args ...any) {
+	var text = fmt.Sprintf(format, args...)
+	warnings = append(warnings, text)
+	fmt.Fprintf(os.Stderr, "warning: %s\n", text)
+}